@@ -0,0 +1,529 @@
+package bash
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ansiReset is the SGR sequence resetting all attributes.
+const ansiReset = "\x1b[0m"
+
+// TokenKind identifies the type of a Token.
+type TokenKind int
+
+const (
+	// TokenText is printable text without escape sequences.
+	TokenText TokenKind = iota
+
+	// TokenSGR is a Select Graphic Rendition sequence like "\x1b[1;31m"
+	// setting colors and text attributes.
+	TokenSGR
+
+	// TokenControl is any other escape sequence: cursor movement, screen
+	// clearing, window titles and so on.
+	TokenControl
+)
+
+// Token is a piece of terminal output returned by Tokenize.
+type Token struct {
+	Kind TokenKind
+
+	// Value is the raw text of the token including the escape characters.
+	Value string
+
+	// Params holds the parameters of a TokenSGR. Omitted parameters are 0,
+	// so "\x1b[m" and "\x1b[;1m" yield [0] and [0 1].
+	Params []int
+}
+
+// ColorMode identifies how a Color is specified.
+type ColorMode int
+
+const (
+	// ColorDefault is the default color of the terminal.
+	ColorDefault ColorMode = iota
+
+	// ColorIndexed is a color of the 256 color palette. Indexes 0-15 are the
+	// basic and bright colors set by the 30-37, 40-47, 90-97 and 100-107
+	// parameters.
+	ColorIndexed
+
+	// ColorRGB is a 24-bit true color.
+	ColorRGB
+)
+
+// Color is a foreground or background color of a Style.
+type Color struct {
+	Mode  ColorMode
+	Index uint8
+	R     uint8
+	G     uint8
+	B     uint8
+}
+
+// Hex returns the color as "#rrggbb" using the xterm palette for indexed
+// colors. It returns an empty string for ColorDefault.
+func (c Color) Hex() string {
+	r, g, b := c.R, c.G, c.B
+
+	switch c.Mode {
+	case ColorIndexed:
+		r, g, b = paletteRGB(c.Index)
+	case ColorRGB:
+	default:
+		return ""
+	}
+
+	return "#" + hexByte(r) + hexByte(g) + hexByte(b)
+}
+
+// Style is the set of text attributes active at some point of the output.
+// The zero value is the default style.
+type Style struct {
+	Foreground Color
+	Background Color
+	Bold       bool
+	Dim        bool
+	Italic     bool
+	Underline  bool
+	Blink      bool
+	Inverse    bool
+	Hidden     bool
+	Strike     bool
+}
+
+// Apply updates the style with the parameters of an SGR sequence.
+// Unknown parameters are ignored.
+func (s *Style) Apply(params []int) {
+	if len(params) == 0 {
+		*s = Style{}
+
+		return
+	}
+
+	for i := 0; i < len(params); i++ {
+		switch param := params[i]; {
+		case param == 0:
+			*s = Style{}
+		case param == 1:
+			s.Bold = true
+		case param == 2:
+			s.Dim = true
+		case param == 3:
+			s.Italic = true
+		case param == 4 || param == 21:
+			s.Underline = true
+		case param == 5 || param == 6:
+			s.Blink = true
+		case param == 7:
+			s.Inverse = true
+		case param == 8:
+			s.Hidden = true
+		case param == 9:
+			s.Strike = true
+		case param == 22:
+			s.Bold, s.Dim = false, false
+		case param == 23:
+			s.Italic = false
+		case param == 24:
+			s.Underline = false
+		case param == 25:
+			s.Blink = false
+		case param == 27:
+			s.Inverse = false
+		case param == 28:
+			s.Hidden = false
+		case param == 29:
+			s.Strike = false
+		case param >= 30 && param <= 37:
+			s.Foreground = indexedColor(param - 30)
+		case param == 38:
+			color, used := extendedColor(params[i+1:])
+			s.Foreground = color
+			i += used
+		case param == 39:
+			s.Foreground = Color{}
+		case param >= 40 && param <= 47:
+			s.Background = indexedColor(param - 40)
+		case param == 48:
+			color, used := extendedColor(params[i+1:])
+			s.Background = color
+			i += used
+		case param == 49:
+			s.Background = Color{}
+		case param >= 90 && param <= 97:
+			s.Foreground = indexedColor(param - 90 + 8)
+		case param >= 100 && param <= 107:
+			s.Background = indexedColor(param - 100 + 8)
+		}
+	}
+}
+
+// Tokenize splits terminal output into text and escape sequence tokens.
+// Concatenating the values of the tokens gives back str.
+//
+// Example:
+//
+//	for _, token := range Tokenize("\x1b[31mfail\x1b[0m") {
+//	    fmt.Printf("%d %q %v\n", token.Kind, token.Value, token.Params)
+//	}
+//	// 1 "\x1b[31m" [31]
+//	// 0 "fail" []
+//	// 1 "\x1b[0m" [0]
+func Tokenize(str string) []Token {
+	tokens := make([]Token, 0)
+	last := 0
+
+	for _, loc := range ansiRegexp.FindAllStringIndex(str, -1) {
+		if loc[0] > last {
+			tokens = append(tokens, Token{Kind: TokenText, Value: str[last:loc[0]]})
+		}
+
+		tokens = append(tokens, escapeToken(str[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+
+	if last < len(str) {
+		tokens = append(tokens, Token{Kind: TokenText, Value: str[last:]})
+	}
+
+	return tokens
+}
+
+// ToPlain removes all ANSI escape sequences from str. Unlike Strip it keeps
+// trailing newlines, so it can be applied to arbitrary chunks of output.
+func ToPlain(str string) string {
+	return ansiRegexp.ReplaceAllString(str, "")
+}
+
+// ToHTML converts ANSI colored text to HTML. The text is HTML escaped and
+// styled runs are wrapped into <span> elements with inline CSS, so the result
+// can be put into a <pre> element as is. Escape sequences other than SGR are
+// removed.
+//
+// Example:
+//
+//	ToHTML("\x1b[1;31mERROR\x1b[0m a < b")
+//	// <span style="color:#cd0000;font-weight:bold">ERROR</span> a &lt; b
+func ToHTML(str string) string {
+	var (
+		result strings.Builder
+		style  Style
+		opened string
+	)
+
+	for _, token := range Tokenize(str) {
+		switch token.Kind {
+		case TokenSGR:
+			style.Apply(token.Params)
+		case TokenText:
+			css := style.css()
+
+			if opened != "" && opened != css {
+				result.WriteString("</span>")
+
+				opened = ""
+			}
+
+			if opened == "" && css != "" {
+				result.WriteString(`<span style="` + css + `">`)
+
+				opened = css
+			}
+
+			result.WriteString(html.EscapeString(token.Value))
+		case TokenControl:
+		}
+	}
+
+	if opened != "" {
+		result.WriteString("</span>")
+	}
+
+	return result.String()
+}
+
+// markdownReplacer escapes the characters having a meaning in Markdown
+// flavours used by chat services like Discord and Slack.
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `~`, `\~`, `|`, `\|`,
+	`>`, `\>`, `#`, `\#`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`,
+)
+
+// ToMarkdown removes all ANSI escape sequences from str and escapes the
+// Markdown special characters, so console output can be relayed to chat
+// services without being rendered as formatting.
+//
+// Example:
+//
+//	ToMarkdown("\x1b[32m*** player_1 joined ***\x1b[0m")
+//	// \*\*\* player\_1 joined \*\*\*
+func ToMarkdown(str string) string {
+	return markdownReplacer.Replace(ToPlain(str))
+}
+
+// VisibleWidth returns the number of terminal columns str occupies.
+// Escape sequences, control characters and combining marks take no space,
+// East Asian wide characters and emoji take two columns.
+func VisibleWidth(str string) int {
+	width := 0
+
+	for _, token := range Tokenize(str) {
+		if token.Kind != TokenText {
+			continue
+		}
+
+		for _, r := range token.Value {
+			width += runeWidth(r)
+		}
+	}
+
+	return width
+}
+
+// Truncate shortens str to at most width visible columns without breaking
+// escape sequences. When str is cut, tail (e.g. "…") is appended within the
+// width and the attributes are reset if a style is still active, so colors
+// do not leak into the following output. Strings fitting into width are
+// returned unchanged.
+//
+// Example:
+//
+//	Truncate("\x1b[31mconnection refused\x1b[0m", 10, "...")
+//	// "\x1b[31mconnect...\x1b[0m"
+func Truncate(str string, width int, tail string) string {
+	if VisibleWidth(str) <= width {
+		return str
+	}
+
+	limit := max(width-VisibleWidth(tail), 0)
+
+	var (
+		result strings.Builder
+		style  Style
+		used   int
+	)
+
+tokens:
+	for _, token := range Tokenize(str) {
+		if token.Kind != TokenText {
+			if token.Kind == TokenSGR {
+				style.Apply(token.Params)
+			}
+
+			result.WriteString(token.Value)
+
+			continue
+		}
+
+		for _, r := range token.Value {
+			w := runeWidth(r)
+			if used+w > limit {
+				break tokens
+			}
+
+			result.WriteRune(r)
+
+			used += w
+		}
+	}
+
+	result.WriteString(tail)
+
+	if style != (Style{}) {
+		result.WriteString(ansiReset)
+	}
+
+	return result.String()
+}
+
+// css returns the inline CSS of the style or an empty string for the
+// default style.
+func (s *Style) css() string {
+	foreground, background := s.Foreground, s.Background
+
+	if s.Inverse {
+		foreground, background = background, foreground
+
+		// Terminals draw light text on a dark background by default.
+		if foreground.Mode == ColorDefault {
+			foreground = indexedColor(0)
+		}
+
+		if background.Mode == ColorDefault {
+			background = indexedColor(7)
+		}
+	}
+
+	rules := make([]string, 0)
+
+	if hex := foreground.Hex(); hex != "" {
+		rules = append(rules, "color:"+hex)
+	}
+
+	if hex := background.Hex(); hex != "" {
+		rules = append(rules, "background-color:"+hex)
+	}
+
+	if s.Bold {
+		rules = append(rules, "font-weight:bold")
+	}
+
+	if s.Dim {
+		rules = append(rules, "opacity:0.5")
+	}
+
+	if s.Italic {
+		rules = append(rules, "font-style:italic")
+	}
+
+	switch {
+	case s.Underline && s.Strike:
+		rules = append(rules, "text-decoration:underline line-through")
+	case s.Underline:
+		rules = append(rules, "text-decoration:underline")
+	case s.Strike:
+		rules = append(rules, "text-decoration:line-through")
+	}
+
+	if s.Hidden {
+		rules = append(rules, "visibility:hidden")
+	}
+
+	return strings.Join(rules, ";")
+}
+
+// escapeToken classifies an escape sequence matched by ansiRegexp.
+func escapeToken(seq string) Token {
+	body, ok := strings.CutPrefix(seq, "\x1b[")
+	if !ok {
+		body, ok = strings.CutPrefix(seq, "\u009b")
+	}
+
+	if ok {
+		if params, ok := strings.CutSuffix(body, "m"); ok && strings.Trim(params, "0123456789;") == "" {
+			return Token{Kind: TokenSGR, Value: seq, Params: parseSGRParams(params)}
+		}
+	}
+
+	return Token{Kind: TokenControl, Value: seq}
+}
+
+// parseSGRParams parses the ";" separated parameters of an SGR sequence.
+func parseSGRParams(params string) []int {
+	fields := strings.Split(params, ";")
+	result := make([]int, 0, len(fields))
+
+	for _, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			value = 0
+		}
+
+		result = append(result, value)
+	}
+
+	return result
+}
+
+// extendedColor parses the arguments of the 38 and 48 parameters:
+// "5;n" for the 256 color palette and "2;r;g;b" for true color.
+// It returns the color and the number of consumed parameters.
+func extendedColor(params []int) (Color, int) {
+	if len(params) >= 2 && params[0] == 5 {
+		return indexedColor(params[1]), 2
+	}
+
+	if len(params) >= 4 && params[0] == 2 {
+		return Color{
+			Mode: ColorRGB,
+			R:    clampByte(params[1]),
+			G:    clampByte(params[2]),
+			B:    clampByte(params[3]),
+		}, 4
+	}
+
+	return Color{}, len(params)
+}
+
+func indexedColor(index int) Color {
+	return Color{Mode: ColorIndexed, Index: clampByte(index)}
+}
+
+func clampByte(value int) uint8 {
+	return uint8(min(max(value, 0), 255)) //nolint:gosec // clamped above
+}
+
+// basicPalette holds the xterm values of the 16 basic and bright colors.
+var basicPalette = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// cubeLevels are the channel values of the 6x6x6 color cube.
+var cubeLevels = [6]uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+// paletteRGB returns the xterm RGB value of a 256 color palette index.
+func paletteRGB(index uint8) (r, g, b uint8) {
+	switch {
+	case index < 16:
+		color := basicPalette[index]
+
+		return color[0], color[1], color[2]
+	case index < 232:
+		index -= 16
+
+		return cubeLevels[index/36], cubeLevels[index/6%6], cubeLevels[index%6]
+	default:
+		gray := 8 + 10*(index-232)
+
+		return gray, gray, gray
+	}
+}
+
+func hexByte(value uint8) string {
+	const digits = "0123456789abcdef"
+
+	return string([]byte{digits[value>>4], digits[value&0x0f]})
+}
+
+// wideRanges are the code point ranges of East Asian wide and fullwidth
+// characters and emoji occupying two terminal columns.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x2614, 0x2615}, {0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x2705, 0x2705},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x2753, 0x2755}, {0x2795, 0x2797},
+	{0x2e80, 0x303e}, {0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff},
+	{0xa000, 0xa4cf}, {0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff},
+	{0xfe10, 0xfe19}, {0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6},
+	{0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a},
+	{0x1f200, 0x1f251}, {0x1f300, 0x1f64f}, {0x1f680, 0x1f6ff}, {0x1f900, 0x1f9ff},
+	{0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of terminal columns r occupies.
+func runeWidth(r rune) int {
+	if r < 0x20 || (r >= 0x7f && r < 0xa0) || r == 0x200b {
+		return 0
+	}
+
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+
+	for _, wide := range wideRanges {
+		if r < wide[0] {
+			break
+		}
+
+		if r <= wide[1] {
+			return 2
+		}
+	}
+
+	return 1
+}
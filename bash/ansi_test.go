@@ -0,0 +1,193 @@
+package bash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Run("text and sequences", func(t *testing.T) {
+		input := "\x1b[1;31mERROR\x1b[m done\x1b[2K\n"
+		tokens := Tokenize(input)
+
+		assert.Equal(t, []Token{
+			{Kind: TokenSGR, Value: "\x1b[1;31m", Params: []int{1, 31}},
+			{Kind: TokenText, Value: "ERROR"},
+			{Kind: TokenSGR, Value: "\x1b[m", Params: []int{0}},
+			{Kind: TokenText, Value: " done"},
+			{Kind: TokenControl, Value: "\x1b[2K"},
+			{Kind: TokenText, Value: "\n"},
+		}, tokens)
+
+		var joined strings.Builder
+		for _, token := range tokens {
+			joined.WriteString(token.Value)
+		}
+
+		assert.Equal(t, input, joined.String())
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		assert.Equal(t, []Token{{Kind: TokenText, Value: "hello"}}, Tokenize("hello"))
+		assert.Empty(t, Tokenize(""))
+	})
+
+	t.Run("private and osc sequences", func(t *testing.T) {
+		tokens := Tokenize("\x1b[?25l\x1b]0;title\x07\u009b32mok")
+
+		assert.Equal(t, TokenControl, tokens[0].Kind)
+		assert.Equal(t, TokenControl, tokens[1].Kind)
+		assert.Equal(t, Token{Kind: TokenSGR, Value: "\u009b32m", Params: []int{32}}, tokens[2])
+		assert.Equal(t, Token{Kind: TokenText, Value: "ok"}, tokens[3])
+	})
+}
+
+func TestStyleApply(t *testing.T) {
+	var style Style
+
+	style.Apply([]int{1, 4, 33, 44})
+	assert.Equal(t, Style{
+		Foreground: Color{Mode: ColorIndexed, Index: 3},
+		Background: Color{Mode: ColorIndexed, Index: 4},
+		Bold:       true,
+		Underline:  true,
+	}, style)
+
+	style.Apply([]int{22, 24, 39, 49})
+	assert.Equal(t, Style{}, style)
+
+	style.Apply([]int{38, 5, 208, 48, 2, 10, 20, 30, 91})
+	assert.Equal(t, Color{Mode: ColorIndexed, Index: 9}, style.Foreground)
+	assert.Equal(t, Color{Mode: ColorRGB, R: 10, G: 20, B: 30}, style.Background)
+
+	style.Apply([]int{0})
+	assert.Equal(t, Style{}, style)
+
+	style.Apply([]int{7, 103})
+	assert.True(t, style.Inverse)
+	assert.Equal(t, Color{Mode: ColorIndexed, Index: 11}, style.Background)
+}
+
+func TestColorHex(t *testing.T) {
+	assert.Equal(t, "", Color{}.Hex())
+	assert.Equal(t, "#cd0000", Color{Mode: ColorIndexed, Index: 1}.Hex())
+	assert.Equal(t, "#ff8700", Color{Mode: ColorIndexed, Index: 208}.Hex())
+	assert.Equal(t, "#808080", Color{Mode: ColorIndexed, Index: 244}.Hex())
+	assert.Equal(t, "#0a141e", Color{Mode: ColorRGB, R: 10, G: 20, B: 30}.Hex())
+}
+
+func TestToPlain(t *testing.T) {
+	assert.Equal(t, "Hello\n", ToPlain("\x1b[32mHello\x1b[0m\n"))
+}
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no styles",
+			input:    "a < b & c",
+			expected: "a &lt; b &amp; c",
+		},
+		{
+			name:     "bold red",
+			input:    "\x1b[1;31mERROR\x1b[0m a < b",
+			expected: `<span style="color:#cd0000;font-weight:bold">ERROR</span> a &lt; b`,
+		},
+		{
+			name:     "style changes",
+			input:    "\x1b[32mok\x1b[4m!\x1b[24m\x1b[32m?",
+			expected: `<span style="color:#00cd00">ok</span><span style="color:#00cd00;text-decoration:underline">!</span><span style="color:#00cd00">?</span>`,
+		},
+		{
+			name:     "true color background",
+			input:    "\x1b[48;2;255;0;128mx",
+			expected: `<span style="background-color:#ff0080">x</span>`,
+		},
+		{
+			name:     "inverse default colors",
+			input:    "\x1b[7mx\x1b[27my",
+			expected: `<span style="color:#000000;background-color:#e5e5e5">x</span>y`,
+		},
+		{
+			name:     "control sequences removed",
+			input:    "\x1b[2Kline\x1b[1A",
+			expected: "line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ToHTML(tt.input))
+		})
+	}
+}
+
+func TestToMarkdown(t *testing.T) {
+	assert.Equal(t, `\*\*\* player\_1 joined \*\*\*`, ToMarkdown("\x1b[32m*** player_1 joined ***\x1b[0m"))
+	assert.Equal(t, "\\`rm\\` \\[a\\]\\(b\\) \\~\\~x\\~\\~ \\|\\| \\> \\# \\\\", ToMarkdown("`rm` [a](b) ~~x~~ || > # \\"))
+}
+
+func TestVisibleWidth(t *testing.T) {
+	assert.Equal(t, 0, VisibleWidth(""))
+	assert.Equal(t, 5, VisibleWidth("\x1b[1;31mhello\x1b[0m"))
+	assert.Equal(t, 6, VisibleWidth("привет"))
+	assert.Equal(t, 4, VisibleWidth("日本"))
+	assert.Equal(t, 2, VisibleWidth("🚀"))
+	assert.Equal(t, 1, VisibleWidth("é"))
+	assert.Equal(t, 2, VisibleWidth("a\r\nb"))
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		tail     string
+		expected string
+	}{
+		{
+			name:     "fits",
+			input:    "\x1b[31mshort\x1b[0m",
+			width:    5,
+			expected: "\x1b[31mshort\x1b[0m",
+		},
+		{
+			name:     "colored with tail",
+			input:    "\x1b[31mconnection refused\x1b[0m",
+			width:    10,
+			tail:     "...",
+			expected: "\x1b[31mconnect...\x1b[0m",
+		},
+		{
+			name:     "style reset before cut",
+			input:    "\x1b[31mab\x1b[0mcdef",
+			width:    3,
+			expected: "\x1b[31mab\x1b[0mc",
+		},
+		{
+			name:     "wide characters",
+			input:    "日本語",
+			width:    5,
+			tail:     "…",
+			expected: "日本…",
+		},
+		{
+			name:     "tail wider than width",
+			input:    "abcdef",
+			width:    2,
+			tail:     "...",
+			expected: "...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Truncate(tt.input, tt.width, tt.tail))
+		})
+	}
+}
@@ -3,13 +3,14 @@
 package bash
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/outdead/golibs/files"
 )
 
 const (
@@ -21,9 +22,6 @@ const (
 	// - Other common terminal control sequences.
 	ansi = "[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))" //nolint:lll
 
-	// commandBash specifies the default command to use for bash shell execution.
-	commandBash = "bash"
-
 	// zeroValue is the default string value returned when numeric operations fail.
 	zeroValue = "0.0"
 
@@ -32,7 +30,7 @@ const (
 )
 
 // ansiRegexp is a precompiled regular expression created from the ansi pattern.
-// This is used for efficient stripping of ANSI escape sequences from strings.
+// This is used for efficient stripping and tokenizing of ANSI escape sequences.
 var ansiRegexp = regexp.MustCompile(ansi)
 
 // Common error definitions used throughout the package.
@@ -48,6 +46,12 @@ var (
 
 	// ErrBashExecuteFailed indicates that a bash command execution failed.
 	ErrBashExecuteFailed = errors.New("bash execute failed")
+
+	// ErrInvalidPID indicates that a process id is not a positive integer.
+	ErrInvalidPID = errors.New("invalid process id")
+
+	// ErrInvalidProcStat indicates that a proc filesystem file has unexpected format.
+	ErrInvalidProcStat = errors.New("invalid proc stat")
 )
 
 // Strip removes all ANSI escape sequences and trailing newline characters from a string.
@@ -67,7 +71,7 @@ func Strip(str string) string {
 }
 
 // Execute runs a system command and captures its output streams.
-// It provides a convenient wrapper around exec.Command with integrated error handling.
+// It provides a convenient wrapper around ExecuteContext with a background context.
 //
 // Parameters:
 //   - name: The name/path of the command to execute (e.g. "ls", "/bin/bash")
@@ -75,14 +79,15 @@ func Strip(str string) string {
 //
 // Returns:
 //   - string: The combined stdout output of the command
-//   - error:  Returns ErrBashExecuteFailed if stderr contains output,
-//     or the original exec error if the command failed to run.
+//   - error:  Returns *ExecError (matching ErrBashExecuteFailed) if stderr
+//     contains output or the command exited with non-zero status,
+//     or the original exec error if the command failed to start.
 //     Returns nil if execution was successful with empty stderr.
 //
 // Behavior:
 //   - Captures both stdout and stderr streams separately
 //   - Considers any stderr output as an error condition
-//   - Preserves the command's exit status error if present
+//   - Preserves the command's exit status in ExecError.ExitCode
 //   - Trims no output - returned strings may contain trailing newlines
 //
 // Example:
@@ -94,49 +99,63 @@ func Strip(str string) string {
 //	fmt.Println(output)
 //
 // Notes:
-//   - For bash commands, consider using commandBash constant as name
+//   - Use Pipe instead of "bash -c" to combine commands without shell injection risks
 //   - Command output is not stripped of ANSI codes (use Strip() separately)
-//   - Not suitable for interactive commands requiring stdin.
+//   - Not suitable for interactive commands requiring stdin
+//   - Use ExecuteContext or Runner to limit the execution time
+//   - Use a Runner with WithStderrPolicy for tools that print warnings to stderr.
 func Execute(name string, args ...string) (string, error) {
-	var stdout, stder bytes.Buffer
-
-	cmd := exec.Command(name, args...)
-
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stder
-
-	err := cmd.Run()
-
-	// Return stderr if present, even if command technically succeeded.
-	if stder.String() != "" {
-		return stdout.String(), fmt.Errorf("%w: %s", ErrBashExecuteFailed, stder.String())
-	}
-
-	return stdout.String(), err
+	return ExecuteContext(context.Background(), name, args...)
 }
 
-// GetLargeFileList finds large files with specific extension in given path
+// GetLargeFileList finds large files matching a regular expression in given path.
+//
 // Parameters:
-//   - path: directory to search
-//   - ext: file extension to match (e.g. ".log")
+//   - path: directory to search recursively
+//   - mask: regular expression matched against file paths relative to path
+//     (e.g. "\\.log$")
 //   - params: optional count parameter (default 20)
 //
-// Returns list of files or error if command fails.
+// Returns:
+//   - string: one "<size> <path>" line per file, largest first, with sizes in
+//     human readable form like `ls -h` prints them (e.g. "1.5M ./app.log")
+//   - error: invalid mask or error walking the directory tree
+//
+// Notes:
+//   - Use files.FindLargeFiles to get structured results and more filters.
 func GetLargeFileList(path, mask string, params ...int) (string, error) {
-	count := "20"
+	return GetLargeFileListContext(context.Background(), path, mask, params...)
+}
+
+// GetLargeFileListContext is like GetLargeFileList but stops the search as
+// soon as ctx is done.
+func GetLargeFileListContext(ctx context.Context, path, mask string, params ...int) (string, error) {
+	count := 20
 	if len(params) > 0 {
-		count = strconv.Itoa(params[0])
+		count = params[0]
+	}
+
+	pattern, err := regexp.Compile(mask)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidCommand, err)
 	}
 
-	args := []string{
-		"-c", "ls " + path + " -hSRs | egrep '" + mask + "' | head -" + count,
+	entries, err := files.FindLargeFiles(ctx, path, count, files.WithRegexp(pattern))
+	if err != nil {
+		return "", err
+	}
+
+	var list strings.Builder
+
+	for _, entry := range entries {
+		list.WriteString(formatHumanSize(entry.Size) + " " + entry.Path + "\n")
 	}
 
-	return Execute(commandBash, args...)
+	return list.String(), nil
 }
 
 // PidofByProcess retrieves the process ID (PID) of a running process by its name.
-// It uses the system's 'pidof' command to find the PID of the specified process.
+// It scans the proc filesystem the same way the 'pidof' command does.
 //
 // Parameters:
 //   - process: Name of the process to look up (e.g., "nginx", "java").
@@ -145,14 +164,14 @@ func GetLargeFileList(path, mask string, params ...int) (string, error) {
 // Returns:
 //   - string: The PID of the process as a string if found.
 //   - error:  May return:
-//   - Original error from command execution if pidof fails
-//   - ErrEmptyPID if process is not running or pidof returns empty
-//   - Other system errors if command cannot be executed
+//   - ErrEmptyPID if no process with such name exists, the error also
+//     matches ErrNotRunning
+//   - Other system errors if /proc cannot be read
 //
 // Behavior:
-//   - Executes 'pidof <process>' command internally
-//   - Automatically trims trailing newline from output
-//   - Returns first PID if multiple instances are running (pidof behavior)
+//   - Matches the kernel process name (comm) or the base name of argv[0]
+//   - Skips zombie processes
+//   - Returns the lowest PID if multiple instances are running
 //   - Does not validate if the process is actually running beyond PID existence
 //
 // Example:
@@ -168,31 +187,27 @@ func GetLargeFileList(path, mask string, params ...int) (string, error) {
 //	fmt.Printf("Nginx PID: %s\n", pid)
 //
 // Notes:
-//   - Requires pidof command to be available in system PATH
+//   - Does not fork any external command
 //   - For more advanced process lookups, see PidofByProcessAndParam
 //   - Returned PID string may need conversion to int for numeric operations
-//   - On systems with multiple process instances, consider using pgrep instead.
+//   - Use FindProcesses to get all matches with their command lines.
 func PidofByProcess(process string) (string, error) {
-	out, err := Execute("pidof", process)
+	found, err := FindProcesses(process)
 	if err != nil {
 		return "", err
 	}
 
-	if l := strings.Split(out, "\n"); len(l) > 0 {
-		pid := l[0]
-		if pid == "" {
-			return "", ErrEmptyPID
-		}
-
-		return pid, nil
+	if len(found) == 0 {
+		return "", fmt.Errorf("%w: %w", ErrEmptyPID, ErrNotRunning)
 	}
 
-	return "", ErrNotRunning
+	return strconv.Itoa(found[0].PID), nil
 }
 
 // PidofByProcessAndParam finds a process ID by process name and matching parameter.
-// It executes a command pipeline: pgrep -af <process> | grep <param> to locate
-// the specific process instance containing the given parameter.
+// It mimics the pipeline: pgrep -af <process> | grep -v " bash " | grep <param>
+// by scanning the proc filesystem, to locate the specific process instance
+// containing the given parameter.
 //
 // Parameters:
 //   - process: The name of the process to search for (e.g. "java", "nginx")
@@ -201,9 +216,16 @@ func PidofByProcess(process string) (string, error) {
 //
 // Returns:
 //   - string: The PID of the matching process
-//   - error: ErrEmptyPID if process is found but PID is empty,
+//   - error: ErrInvalidCommand if process or param is empty,
 //     ErrNotRunning if no matching process is found,
-//     or other errors from command execution
+//     or other errors from reading /proc
+//
+// Behavior:
+//   - Both process and param are matched as substrings of the command line,
+//     the PID is not part of it
+//   - Command lines containing " bash " are skipped to ignore shell wrappers
+//   - The calling process is never matched, the same way pgrep skips itself
+//   - Returns the lowest matching PID, use FindProcessesByParam to get all
 //
 // Example:
 //
@@ -216,35 +238,20 @@ func PidofByProcessAndParam(process, param string) (string, error) {
 		return "", ErrInvalidCommand
 	}
 
-	if string(param[0]) == "-" {
-		param = "\\" + param
-	}
-
-	cmd := fmt.Sprintf("pgrep -af %q | grep -v %q | grep %q | grep -o -e %q", process, " bash ", param, `^[0-9]*`)
-
-	out, err := Execute(commandBash, "-c", cmd)
+	found, err := FindProcessesByParam(process, param)
 	if err != nil {
-		if err.Error() == "exit status 1" {
-			return "", ErrNotRunning
-		}
-
-		return out, err
+		return "", err
 	}
 
-	if l := strings.Split(out, "\n"); len(l) > 0 {
-		pid := l[0]
-		if pid == "" {
-			return "", ErrEmptyPID
-		}
-
-		return pid, nil
+	if len(found) == 0 {
+		return "", ErrNotRunning
 	}
 
-	return "", ErrNotRunning
+	return strconv.Itoa(found[0].PID), nil
 }
 
 // GetUptimeByPID retrieves the elapsed time since a process started using its PID.
-// It executes the 'ps' command to get the process's running duration in format [[DD-]HH:]MM:SS.
+// The start time is read from /proc/<pid>/stat and formatted as [[DD-]HH:]MM:SS.
 //
 // Parameters:
 //   - pid: The process ID as a string (e.g., "12345"). Must be a valid running process ID.
@@ -254,16 +261,11 @@ func PidofByProcessAndParam(process, param string) (string, error) {
 //   - "MM:SS" for processes running <1 hour
 //   - "HH:MM:SS" for processes running <1 day
 //   - "DD-HH:MM:SS" for processes running multiple days
-//   - uptimeZeroValue ("00:00:00") if the process is not found
+//   - uptimeZeroValue ("00:00:00") on error
 //   - error:  Returns:
-//   - Original error if 'ps' command execution fails
-//   - nil if successful (even if process not found)
-//
-// Behavior:
-//   - Uses 'ps -o etime= -p PID' command to get process duration
-//   - Automatically trims whitespace and newlines from output
-//   - Returns zero value (not error) if process doesn't exist
-//   - Output format matches system 'ps' command behavior
+//   - ErrInvalidPID if pid is not a number
+//   - ErrNotRunning if the process is not found
+//   - nil if successful
 //
 // Example:
 //
@@ -274,17 +276,16 @@ func PidofByProcessAndParam(process, param string) (string, error) {
 //	fmt.Printf("Process running for: %s", uptime) // e.g. "01:23:45"
 //
 // Notes:
-//   - Requires 'ps' command to be available in system PATH
-//   - Unlike other functions, returns zero value rather than error for missing process
+//   - Output format matches `ps -o etime` behavior
 //   - For empty/zero uptime, check against uptimeZeroValue constant
 //   - Uptime resolution is seconds (no milliseconds).
 func GetUptimeByPID(pid string) (string, error) {
-	uptime, err := Execute("ps", "-o", "etime=", "-p", pid)
+	info, err := readProcessByPID(pid)
 	if err != nil {
 		return uptimeZeroValue, err
 	}
 
-	return strings.Trim(uptime, " \n"), nil
+	return formatElapsed(info.Uptime()), nil
 }
 
 // CPUPercentByPID retrieves the CPU usage percentage for a specific process.
@@ -295,17 +296,14 @@ func GetUptimeByPID(pid string) (string, error) {
 //
 // Returns:
 //   - string: CPU usage percentage with "%" suffix (e.g., "25.5%")
-//     Returns zeroValue + "%" ("0.0%") if:
-//   - Process is not found
-//   - Process is using 0% CPU
-//   - Command fails
-//   - error:  Error from command execution if ps command fails,
+//     Returns zeroValue + "%" ("0.0%") on error.
+//   - error:  ErrInvalidPID, ErrNotRunning or an error reading /proc,
 //     nil if successful (even if process shows 0% usage)
 //
 // Behavior:
-//   - Uses 'ps S -p PID -o pcpu=' command to get CPU percentage
-//   - The 'S' option includes child processes in calculation
-//   - Automatically trims whitespace and appends "%" symbol
+//   - Divides the CPU time from /proc/<pid>/stat by the process uptime,
+//     the same way 'ps S -o pcpu' does
+//   - CPU time of waited-for children is included in calculation
 //   - Returns string formatted to one decimal place
 //
 // Example:
@@ -318,17 +316,22 @@ func GetUptimeByPID(pid string) (string, error) {
 //
 // Notes:
 //   - CPU percentage is relative to a single core (may exceed 100% on multicore systems)
-//   - Requires 'ps' command to be available in system PATH
 //   - For containerized processes, results may differ from host metrics
-//   - Values are snapshots, not averages over time
-//   - Consider using multiple samples for monitoring trending usage.
+//   - Values are lifetime averages, not instantaneous usage,
+//     use SampleCPU or CPUSampler to measure the current load
+//   - Use ProcessCPUPercent to get the value as float64.
 func CPUPercentByPID(pid string) (string, error) {
-	cpu, err := Execute("ps", "S", "-p", pid, "-o", "pcpu=")
+	id, err := parsePID(pid)
 	if err != nil {
 		return zeroValue + "%", err
 	}
 
-	return strings.Trim(cpu, " \n") + "%", nil
+	percent, err := ProcessCPUPercent(id)
+	if err != nil {
+		return zeroValue + "%", err
+	}
+
+	return formatPercent(percent), nil
 }
 
 // MemPercentByPID retrieves the memory usage percentage for a specific process.
@@ -339,17 +342,13 @@ func CPUPercentByPID(pid string) (string, error) {
 //
 // Returns:
 //   - string: Memory usage percentage with "%" suffix (e.g., "4.2%")
-//     Returns zeroValue + "%" ("0.0%") if:
-//   - Process is not found
-//   - Process uses 0% memory
-//   - Command execution fails
-//   - error:  Error from command execution if ps command fails,
+//     Returns zeroValue + "%" ("0.0%") on error.
+//   - error:  ErrInvalidPID, ErrNotRunning or an error reading /proc,
 //     nil if successful (even if process shows 0% usage)
 //
 // Behavior:
-//   - Uses 'ps S -p PID -o pmem=' command to get memory percentage
-//   - The 'S' option includes child processes in calculation
-//   - Automatically trims whitespace and appends "%" symbol
+//   - Divides the RSS from /proc/<pid>/statm by MemTotal from /proc/meminfo,
+//     the same way 'ps -o pmem' does
 //   - Returns string formatted to one decimal place
 //
 // Example:
@@ -363,37 +362,36 @@ func CPUPercentByPID(pid string) (string, error) {
 // Notes:
 //   - Percentage is relative to total physical memory (RAM)
 //   - Does not include shared memory or swap usage
-//   - Requires 'ps' command to be available in system PATH
-//   - Values represent current snapshot, not averages over time.
+//   - Values represent current snapshot, not averages over time
+//   - Use ProcessMemPercent to get the value as float64.
 func MemPercentByPID(pid string) (string, error) {
-	mem, err := Execute("ps", "S", "-p", pid, "-o", "pmem=")
+	id, err := parsePID(pid)
+	if err != nil {
+		return zeroValue + "%", err
+	}
+
+	percent, err := ProcessMemPercent(id)
 	if err != nil {
 		return zeroValue + "%", err
 	}
 
-	return strings.Trim(mem, " \n") + "%", nil
+	return formatPercent(percent), nil
 }
 
-// MemUsedByPID calculates the total resident memory usage of a process and its children in megabytes.
-// It sums the RSS (Resident Set Size) memory of all process threads and converts to MB.
+// MemUsedByPID calculates the resident memory usage of a process in megabytes.
 //
 // Parameters:
 //   - pid: The process ID as a string (e.g., "1234"). Must be a valid running process.
 //
 // Returns:
 //   - string: Memory usage formatted with " MB" suffix (e.g., "24.5 MB")
-//     Returns zeroValue + " MB" ("0.0 MB") if:
-//   - Process is not found
-//   - Process uses no resident memory
-//   - Command execution fails
-//   - error:  Error from command execution if the bash command fails,
+//     Returns zeroValue + " MB" ("0.0 MB") on error.
+//   - error:  ErrInvalidPID, ErrNotRunning or an error reading /proc,
 //     nil if successful (even if memory usage is 0)
 //
 // Implementation Details:
-//   - Uses bash command pipeline:
-//     1. `ps -ylp PID` lists all threads with memory info
-//     2. `awk` sums the RSS (column 8) and converts to MB (/1024)
-//   - Automatically trims whitespace/newlines from output
+//   - Reads the resident pages from /proc/<pid>/statm
+//   - Returns string formatted to one decimal place
 //   - Adds " MB" suffix to clarify units
 //
 // Example:
@@ -408,18 +406,19 @@ func MemPercentByPID(pid string) (string, error) {
 //   - Measures physical RAM usage (RSS), not virtual memory
 //   - Includes memory used by all process threads
 //   - Values are in binary megabytes (MiB, 1024-based)
-//   - Requires GNU ps and awk utilities.
+//   - Use ProcessMemUsed to get the value in bytes.
 func MemUsedByPID(pid string) (string, error) {
-	args := []string{
-		"-c", "ps -ylp " + pid + " | awk '{x += $8} END {print \"\" x/1024;}'",
+	id, err := parsePID(pid)
+	if err != nil {
+		return zeroValue + " MB", err
 	}
 
-	mem, err := Execute(commandBash, args...)
+	used, err := ProcessMemUsed(id)
 	if err != nil {
 		return zeroValue + " MB", err
 	}
 
-	return strings.Trim(mem, " \n") + " MB", nil
+	return formatMegabytes(used, 1), nil
 }
 
 // MemUsed retrieves the total used system memory in megabytes (MB).
@@ -427,19 +426,13 @@ func MemUsedByPID(pid string) (string, error) {
 //
 // Returns:
 //   - string: Total used memory formatted with " MB" suffix (e.g., "2048 MB")
-//     Returns zeroValue + " MB" ("0.0 MB") if:
-//   - Command execution fails
-//   - Unable to parse memory information
-//   - error:  Error from command execution if the bash command fails,
-//     nil if successful
+//     Returns zeroValue + " MB" ("0.0 MB") if /proc/meminfo cannot be read or parsed.
+//   - error:  Error from reading /proc/meminfo, nil if successful
 //
 // Implementation Details:
-//   - Uses bash command pipeline:
-//     1. `free` command to get memory statistics
-//     2. `awk` extracts the used memory value (column 3 from second line)
-//     3. Converts from kilobytes to megabytes (/1024)
-//     4. Formats as integer (%.0f) to remove decimal places
-//   - Automatically trims whitespace/newlines from output
+//   - Takes SystemMemory.Used from ReadSystemMemory, which matches the
+//     "used" column of the `free` command
+//   - Formats as integer to remove decimal places
 //   - Adds " MB" suffix to clarify units
 //
 // Example:
@@ -453,40 +446,28 @@ func MemUsedByPID(pid string) (string, error) {
 // Notes:
 //   - Measures actual used memory excluding buffers/cache
 //   - Values are in binary megabytes (MiB, 1024-based)
-//   - Requires GNU free and awk utilities
 //   - Represents system-wide memory usage, not per-process
-//   - Consider using /proc/meminfo for more detailed breakdown.
+//   - Use ReadSystemMemory for typed values and a more detailed breakdown.
 func MemUsed() (string, error) {
-	args := []string{
-		"-c", "free | awk 'NR==2 { printf(\"%.0f\", $3/1024); }'",
-	}
-
-	mem, err := Execute(commandBash, args...)
+	mem, err := ReadSystemMemory()
 	if err != nil {
 		return zeroValue + " MB", err
 	}
 
-	return strings.Trim(mem, " \n") + " MB", nil
+	return formatMegabytes(mem.Used, 0), nil
 }
 
-// MemAvail retrieves the total available system memory in megabytes (MB).
-// This represents the physical RAM available for new processes, excluding buffers/cache.
+// MemAvail retrieves the total physical memory of the system in megabytes (MB).
 //
 // Returns:
-//   - string: Total available memory formatted with " MB" suffix (e.g., "8192 MB")
-//     Returns zeroValue + " MB" ("0.0 MB") if:
-//   - Command execution fails
-//   - Unable to parse memory information
-//   - error:  Error from command execution if the bash command fails,
-//     nil if successful
+//   - string: Total memory formatted with " MB" suffix (e.g., "8192 MB")
+//     Returns zeroValue + " MB" ("0.0 MB") if /proc/meminfo cannot be read or parsed.
+//   - error:  Error from reading /proc/meminfo, nil if successful
 //
 // Implementation Details:
-//   - Uses bash command pipeline:
-//     1. `free` command to get memory statistics
-//     2. `awk` extracts the total memory value (column 2 from second line)
-//     3. Converts from kilobytes to megabytes (/1024)
-//     4. Formats as integer (%.0f) for clean output
-//   - Automatically trims whitespace/newlines from output
+//   - Takes SystemMemory.Total from ReadSystemMemory, which matches the
+//     "total" column of the `free` command
+//   - Formats as integer for clean output
 //   - Adds " MB" suffix to clarify units
 //
 // Example:
@@ -500,18 +481,13 @@ func MemUsed() (string, error) {
 // Notes:
 //   - Measures physical RAM, not including swap space
 //   - Values are in binary megabytes (MiB, 1024-based)
-//   - Requires GNU free and awk utilities
-//   - Represents system-wide available memory
-//   - For accurate container memory limits, check cgroup settings.
+//   - Use ReadSystemMemory().Available for memory available to new processes
+//   - Container limits are ignored, use ReadResourceLimits inside containers.
 func MemAvail() (string, error) {
-	args := []string{
-		"-c", "free | awk 'NR==2 { printf(\"%.0f\", $2/1024); }'",
-	}
-
-	mem, err := Execute(commandBash, args...)
+	mem, err := ReadSystemMemory()
 	if err != nil {
 		return zeroValue + " MB", err
 	}
 
-	return strings.Trim(mem, " \n") + " MB", nil
+	return formatMegabytes(mem.Total, 0), nil
 }
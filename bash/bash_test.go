@@ -58,18 +58,24 @@ func TestPidofByProcess(t *testing.T) {
 
 	t.Run("expect error", func(t *testing.T) {
 		_, err := PidofByProcess(NonExistentProcessName)
-		assert.NotNil(t, err)
+		assert.ErrorIs(t, err, ErrEmptyPID)
+		assert.ErrorIs(t, err, ErrNotRunning)
 	})
 }
 
 func TestPidofByProcessAndParam(t *testing.T) {
 	t.Run("no errors", func(t *testing.T) {
-		expectPid := strconv.Itoa(os.Getpid())
-		expectName := filepath.Base(os.Args[0])
+		cmd := startSleep(t, "31.5")
 
-		pid, err := PidofByProcessAndParam(expectName, expectPid)
+		pid, err := PidofByProcessAndParam("sleep", "31.5")
 		assert.Nil(t, err)
-		assert.Equal(t, expectPid, pid)
+		assert.Equal(t, strconv.Itoa(cmd.Process.Pid), pid)
+	})
+
+	t.Run("current process", func(t *testing.T) {
+		pid, err := PidofByProcessAndParam(filepath.Base(os.Args[0]), os.Args[0])
+		assert.ErrorIs(t, err, ErrNotRunning)
+		assert.Empty(t, pid)
 	})
 
 	t.Run("expect error", func(t *testing.T) {
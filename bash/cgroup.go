@@ -0,0 +1,391 @@
+package bash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/outdead/golibs/files"
+)
+
+// cgroupPath is the mount point of the cgroup filesystem. It is a variable so
+// tests can point the helpers at a fake tree.
+var cgroupPath = "/sys/fs/cgroup"
+
+// CgroupVersion identifies the cgroup hierarchy used by the host.
+type CgroupVersion int
+
+const (
+	// CgroupNone means no cgroup filesystem was found.
+	CgroupNone CgroupVersion = iota
+
+	// CgroupV1 is the legacy hierarchy with one tree per controller.
+	CgroupV1
+
+	// CgroupV2 is the unified hierarchy.
+	CgroupV2
+)
+
+// String returns a human readable name of the version.
+func (v CgroupVersion) String() string {
+	switch v {
+	case CgroupV1:
+		return "v1"
+	case CgroupV2:
+		return "v2"
+	default:
+		return "none"
+	}
+}
+
+// ResourceLimits describes the memory and CPU a process may effectively use.
+// Values come from the cgroup of the process when it is limited and from the
+// host otherwise.
+type ResourceLimits struct {
+	// Cgroup is the detected cgroup version.
+	Cgroup CgroupVersion
+
+	// MemoryLimit is the memory limit in bytes. It equals the host total
+	// memory when MemoryLimited is false.
+	MemoryLimit uint64
+
+	// MemoryUsage is the memory used in bytes: the cgroup usage (including
+	// page cache) when MemoryLimited is true, the host used memory otherwise.
+	MemoryUsage uint64
+
+	// MemoryLimited reports whether a cgroup memory limit is set.
+	MemoryLimited bool
+
+	// CPULimit is the number of cores the process may use. It may be
+	// fractional for cgroup quotas and equals the host core count when
+	// CPULimited is false.
+	CPULimit float64
+
+	// CPULimited reports whether a cgroup CPU quota is set.
+	CPULimited bool
+}
+
+// MemoryPercent returns MemoryUsage as a percentage of MemoryLimit.
+func (l *ResourceLimits) MemoryPercent() float64 {
+	if l.MemoryLimit == 0 {
+		return 0
+	}
+
+	return float64(l.MemoryUsage) / float64(l.MemoryLimit) * 100
+}
+
+// DetectCgroupVersion reports which cgroup hierarchy is mounted. Hybrid
+// setups with both hierarchies mounted are reported as CgroupV1 because the
+// controllers live in the legacy trees there.
+func DetectCgroupVersion() CgroupVersion {
+	if files.FileExists(filepath.Join(cgroupPath, "cgroup.controllers")) {
+		return CgroupV2
+	}
+
+	for _, controller := range []string{"memory", "cpu", "cpu,cpuacct"} {
+		if files.DirExists(filepath.Join(cgroupPath, controller)) {
+			return CgroupV1
+		}
+	}
+
+	return CgroupNone
+}
+
+// ReadResourceLimits returns the effective resource limits of the process
+// with the given PID, or of the current process if pid is 0.
+//
+// Behavior:
+//   - Detects cgroup v1 and v2 and reads memory.max/memory.current/cpu.max
+//     (v2) or memory.limit_in_bytes/memory.usage_in_bytes/cpu.cfs_quota_us (v1)
+//   - Walks up the cgroup tree and reports the lowest limit of all ancestors
+//   - Falls back to host values from /proc when there is no limit
+//
+// Example:
+//
+//	limits, err := ReadResourceLimits(0)
+//	if err != nil {
+//	    return err
+//	}
+//	if limits.MemoryPercent() > 90 {
+//	    // memory almost full, inside or outside of a container
+//	}
+func ReadResourceLimits(pid int) (*ResourceLimits, error) {
+	proc := "self"
+	if pid > 0 {
+		proc = strconv.Itoa(pid)
+	}
+
+	limits := &ResourceLimits{Cgroup: DetectCgroupVersion()}
+
+	if limits.Cgroup != CgroupNone {
+		paths, err := readCgroupPaths(filepath.Join(procPath, proc, "cgroup"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%w: %s", ErrNotRunning, proc)
+			}
+
+			return nil, err
+		}
+
+		if err := readCgroupLimits(limits, paths); err != nil {
+			return nil, err
+		}
+	}
+
+	if !limits.MemoryLimited {
+		mem, err := ReadSystemMemory()
+		if err != nil {
+			return nil, err
+		}
+
+		limits.MemoryLimit = mem.Total
+		limits.MemoryUsage = mem.Used
+	}
+
+	if !limits.CPULimited {
+		stat, err := ReadCPUStat()
+		if err != nil {
+			return nil, err
+		}
+
+		limits.CPULimit = float64(len(stat.Cores))
+	}
+
+	return limits, nil
+}
+
+// readCgroupLimits fills the cgroup part of limits.
+func readCgroupLimits(limits *ResourceLimits, paths map[string]string) error {
+	var memory, cpu []string
+
+	if limits.Cgroup == CgroupV2 {
+		dir := cgroupDir(cgroupPath, paths[""])
+		memory = []string{dir}
+		cpu = []string{dir}
+	} else {
+		memory = cgroupV1Dirs(paths, "memory")
+		cpu = cgroupV1Dirs(paths, "cpu")
+	}
+
+	for _, dir := range memory {
+		if err := readMemoryLimit(limits, dir); err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range cpu {
+		if err := readCPULimit(limits, dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMemoryLimit reads the memory limit and usage of a cgroup directory and
+// its ancestors.
+func readMemoryLimit(limits *ResourceLimits, dir string) error {
+	limitFile, usageFile := "memory.max", "memory.current"
+	if limits.Cgroup == CgroupV1 {
+		limitFile, usageFile = "memory.limit_in_bytes", "memory.usage_in_bytes"
+	}
+
+	usage, ok, err := readCgroupValue(filepath.Join(dir, usageFile))
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		limits.MemoryUsage = usage
+	}
+
+	for _, current := range cgroupAncestors(dir) {
+		limit, ok, err := readCgroupValue(filepath.Join(current, limitFile))
+		if err != nil {
+			return err
+		}
+
+		// cgroup v1 reports "unlimited" as a huge page-aligned number.
+		if !ok || limit >= 1<<62 {
+			continue
+		}
+
+		if !limits.MemoryLimited || limit < limits.MemoryLimit {
+			limits.MemoryLimit = limit
+			limits.MemoryLimited = true
+		}
+	}
+
+	return nil
+}
+
+// readCPULimit reads the CPU quota of a cgroup directory and its ancestors.
+func readCPULimit(limits *ResourceLimits, dir string) error {
+	for _, current := range cgroupAncestors(dir) {
+		var (
+			quota, period string
+			err           error
+		)
+
+		if limits.Cgroup == CgroupV2 {
+			var content []byte
+
+			content, err = os.ReadFile(filepath.Join(current, "cpu.max"))
+			if err == nil {
+				quota, period, _ = strings.Cut(strings.TrimSpace(string(content)), " ")
+			}
+		} else {
+			quota, err = readTrimmed(filepath.Join(current, "cpu.cfs_quota_us"))
+			if err == nil {
+				period, err = readTrimmed(filepath.Join(current, "cpu.cfs_period_us"))
+			}
+		}
+
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		cores, ok := parseCPUQuota(quota, period)
+		if !ok {
+			continue
+		}
+
+		if !limits.CPULimited || cores < limits.CPULimit {
+			limits.CPULimit = cores
+			limits.CPULimited = true
+		}
+	}
+
+	return nil
+}
+
+// readCgroupPaths parses /proc/<pid>/cgroup into a map of controller to
+// cgroup path. The unified (v2) hierarchy is stored under the empty key.
+func readCgroupPaths(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]string)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if parts[1] == "" {
+			paths[""] = parts[2]
+
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+
+	return paths, nil
+}
+
+// cgroupV1Dirs returns the candidate directories of a v1 controller. The
+// controller may be mounted alone or co-mounted with others (cpu,cpuacct).
+func cgroupV1Dirs(paths map[string]string, controller string) []string {
+	path, ok := paths[controller]
+	if !ok {
+		return nil
+	}
+
+	for _, mount := range []string{controller, "cpu,cpuacct", "cpuacct,cpu"} {
+		root := filepath.Join(cgroupPath, mount)
+		if files.DirExists(root) {
+			return []string{cgroupDir(root, path)}
+		}
+	}
+
+	return nil
+}
+
+// cgroupDir joins a hierarchy root with a cgroup path. Without a cgroup
+// namespace the path of a containerised process refers to the host tree,
+// which is not visible inside the container, so the root is used instead.
+func cgroupDir(root, path string) string {
+	dir := filepath.Join(root, path)
+	if files.DirExists(dir) {
+		return dir
+	}
+
+	return root
+}
+
+// cgroupAncestors returns dir and all its parents up to the cgroup mount.
+func cgroupAncestors(dir string) []string {
+	dirs := []string{dir}
+
+	for dir != cgroupPath && strings.HasPrefix(dir, cgroupPath+string(filepath.Separator)) {
+		dir = filepath.Dir(dir)
+		dirs = append(dirs, dir)
+	}
+
+	return dirs
+}
+
+// readCgroupValue reads a numeric cgroup file. It returns ok == false if the
+// file does not exist or holds "max".
+func readCgroupValue(path string) (uint64, bool, error) {
+	content, err := readTrimmed(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, false, nil
+		}
+
+		return 0, false, err
+	}
+
+	if content == "max" || content == "" {
+		return 0, false, nil
+	}
+
+	value, err := strconv.ParseUint(content, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return value, true, nil
+}
+
+// parseCPUQuota converts a quota and a period to a number of cores.
+func parseCPUQuota(quota, period string) (float64, bool) {
+	if quota == "max" || quota == "-1" {
+		return 0, false
+	}
+
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+
+	return q / p, true
+}
+
+func readTrimmed(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
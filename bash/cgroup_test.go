@@ -0,0 +1,156 @@
+package bash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupCgroupTest points procPath and cgroupPath at fake trees populated
+// with files and restores the real paths when the test ends.
+func setupCgroupTest(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	root := t.TempDir()
+
+	files["proc/meminfo"] = testMeminfo
+	files["proc/stat"] = "cpu  1 0 1 1 0 0 0 0\ncpu0 1 0 1 1 0 0 0 0\ncpu1 0 0 0 0 0 0 0 0\nbtime 1\n"
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "cgroup"), 0o755))
+
+	oldProc, oldCgroup := procPath, cgroupPath
+	procPath, cgroupPath = filepath.Join(root, "proc"), filepath.Join(root, "cgroup")
+
+	t.Cleanup(func() {
+		procPath, cgroupPath = oldProc, oldCgroup
+	})
+}
+
+func TestReadResourceLimits(t *testing.T) {
+	t.Run("current process", func(t *testing.T) {
+		limits, err := ReadResourceLimits(0)
+		require.NoError(t, err)
+
+		assert.Positive(t, limits.MemoryLimit)
+		assert.Positive(t, limits.CPULimit)
+	})
+
+	t.Run("cgroup v2", func(t *testing.T) {
+		setupCgroupTest(t, map[string]string{
+			"proc/42/cgroup":                          "0::/kubepods/pod1/app\n",
+			"cgroup/cgroup.controllers":               "cpu memory\n",
+			"cgroup/kubepods/memory.max":              "2147483648\n",
+			"cgroup/kubepods/pod1/memory.max":         "max\n",
+			"cgroup/kubepods/pod1/app/memory.max":     "1073741824\n",
+			"cgroup/kubepods/pod1/app/memory.current": "536870912\n",
+			"cgroup/kubepods/pod1/app/cpu.max":        "150000 100000\n",
+		})
+
+		assert.Equal(t, CgroupV2, DetectCgroupVersion())
+
+		limits, err := ReadResourceLimits(42)
+		require.NoError(t, err)
+
+		assert.Equal(t, &ResourceLimits{
+			Cgroup:        CgroupV2,
+			MemoryLimit:   1 << 30,
+			MemoryUsage:   1 << 29,
+			MemoryLimited: true,
+			CPULimit:      1.5,
+			CPULimited:    true,
+		}, limits)
+		assert.InDelta(t, 50.0, limits.MemoryPercent(), 0.001)
+	})
+
+	t.Run("cgroup v2 parent limit", func(t *testing.T) {
+		setupCgroupTest(t, map[string]string{
+			"proc/self/cgroup":          "0::/app\n",
+			"cgroup/cgroup.controllers": "cpu memory\n",
+			"cgroup/app/memory.max":     "max\n",
+			"cgroup/app/memory.current": "1024\n",
+			"cgroup/app/cpu.max":        "max 100000\n",
+		})
+
+		limits, err := ReadResourceLimits(0)
+		require.NoError(t, err)
+
+		assert.False(t, limits.MemoryLimited)
+		assert.Equal(t, uint64(8000000*1024), limits.MemoryLimit)
+		assert.Equal(t, uint64(3000000*1024), limits.MemoryUsage)
+		assert.False(t, limits.CPULimited)
+		assert.Equal(t, 2.0, limits.CPULimit)
+	})
+
+	t.Run("cgroup v1", func(t *testing.T) {
+		setupCgroupTest(t, map[string]string{
+			"proc/42/cgroup":                                  "4:memory:/docker/abc\n2:cpu,cpuacct:/docker/abc\n0::/\n",
+			"cgroup/memory/memory.limit_in_bytes":             "9223372036854771712\n",
+			"cgroup/memory/docker/abc/memory.limit_in_bytes":  "268435456\n",
+			"cgroup/memory/docker/abc/memory.usage_in_bytes":  "134217728\n",
+			"cgroup/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "50000\n",
+			"cgroup/cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+		})
+
+		assert.Equal(t, CgroupV1, DetectCgroupVersion())
+
+		limits, err := ReadResourceLimits(42)
+		require.NoError(t, err)
+
+		assert.Equal(t, &ResourceLimits{
+			Cgroup:        CgroupV1,
+			MemoryLimit:   1 << 28,
+			MemoryUsage:   1 << 27,
+			MemoryLimited: true,
+			CPULimit:      0.5,
+			CPULimited:    true,
+		}, limits)
+	})
+
+	t.Run("cgroup v1 without namespace", func(t *testing.T) {
+		// The host path from /proc/<pid>/cgroup is not mounted inside the
+		// container, the limits are found in the root of the hierarchy.
+		setupCgroupTest(t, map[string]string{
+			"proc/42/cgroup":                      "4:memory:/docker/abc\n",
+			"cgroup/memory/memory.limit_in_bytes": "268435456\n",
+			"cgroup/memory/memory.usage_in_bytes": "1024\n",
+		})
+
+		limits, err := ReadResourceLimits(42)
+		require.NoError(t, err)
+
+		assert.True(t, limits.MemoryLimited)
+		assert.Equal(t, uint64(1<<28), limits.MemoryLimit)
+		assert.Equal(t, uint64(1024), limits.MemoryUsage)
+	})
+
+	t.Run("no cgroup", func(t *testing.T) {
+		setupCgroupTest(t, map[string]string{})
+
+		assert.Equal(t, CgroupNone, DetectCgroupVersion())
+
+		limits, err := ReadResourceLimits(42)
+		require.NoError(t, err)
+
+		assert.Equal(t, CgroupNone, limits.Cgroup)
+		assert.False(t, limits.MemoryLimited)
+		assert.Equal(t, uint64(8000000*1024), limits.MemoryLimit)
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		setupCgroupTest(t, map[string]string{
+			"cgroup/cgroup.controllers": "cpu memory\n",
+		})
+
+		_, err := ReadResourceLimits(42)
+		assert.ErrorIs(t, err, ErrNotRunning)
+	})
+}
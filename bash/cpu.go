@@ -0,0 +1,290 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CPUTimes holds the cumulative time a CPU has spent in each mode since boot,
+// as reported by a "cpu" line of /proc/stat.
+type CPUTimes struct {
+	User    time.Duration
+	Nice    time.Duration
+	System  time.Duration
+	Idle    time.Duration
+	IOWait  time.Duration
+	IRQ     time.Duration
+	SoftIRQ time.Duration
+	Steal   time.Duration
+}
+
+// Total returns the sum of all modes. Guest time is already accounted in User.
+func (t CPUTimes) Total() time.Duration {
+	return t.User + t.Nice + t.System + t.Idle + t.IOWait + t.IRQ + t.SoftIRQ + t.Steal
+}
+
+// Busy returns the time spent doing work, i.e. Total without Idle and IOWait.
+func (t CPUTimes) Busy() time.Duration {
+	return t.Total() - t.Idle - t.IOWait
+}
+
+// CPUStat holds the aggregated and per-core CPU times of the host.
+type CPUStat struct {
+	Total CPUTimes
+	Cores []CPUTimes
+}
+
+// LoadAverage holds the system load averages from /proc/loadavg.
+type LoadAverage struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+}
+
+// CPUUsage is the CPU utilisation measured over a sampling window.
+type CPUUsage struct {
+	// Window is the wall time between the two samples.
+	Window time.Duration
+
+	// Total is the host utilisation in percent of all cores (0-100).
+	Total float64
+
+	// Cores holds the utilisation of every core in percent (0-100).
+	Cores []float64
+
+	// Load holds the load averages at the end of the window.
+	Load LoadAverage
+
+	// Processes maps the sampled PIDs to their utilisation in percent of a
+	// single core, so a process may exceed 100 on multicore systems.
+	// Processes that exited during the window are omitted.
+	Processes map[int]float64
+}
+
+// ReadCPUStat reads the cumulative CPU times from /proc/stat.
+func ReadCPUStat() (*CPUStat, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &CPUStat{}
+
+	var found bool
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		times, err := parseCPUTimes(fields[1:])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", fields[0], err)
+		}
+
+		if fields[0] == "cpu" {
+			stat.Total = times
+			found = true
+		} else {
+			stat.Cores = append(stat.Cores, times)
+		}
+	}
+
+	if !found {
+		return nil, ErrInvalidProcStat
+	}
+
+	return stat, nil
+}
+
+// ReadLoadAverage reads the 1, 5 and 15 minute load averages from /proc/loadavg.
+func ReadLoadAverage() (*LoadAverage, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "loadavg"))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 {
+		return nil, ErrInvalidProcStat
+	}
+
+	loads := make([]float64, 3)
+
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, fmt.Errorf("parse loadavg: %w", err)
+		}
+	}
+
+	return &LoadAverage{Load1: loads[0], Load5: loads[1], Load15: loads[2]}, nil
+}
+
+// SampleCPU measures the host and process CPU utilisation over window.
+// It blocks for the whole window unless ctx is done earlier.
+//
+// Example:
+//
+//	usage, err := SampleCPU(ctx, time.Second, pid)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Printf("host: %.1f%%, server: %.1f%%", usage.Total, usage.Processes[pid])
+func SampleCPU(ctx context.Context, window time.Duration, pids ...int) (*CPUUsage, error) {
+	sampler := NewCPUSampler(pids...)
+
+	if _, err := sampler.Sample(); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return sampler.Sample()
+}
+
+// CPUSampler measures CPU utilisation between consecutive calls to Sample.
+// It is meant to be driven periodically, e.g. from a jobticker.Ticker:
+//
+//	sampler := NewCPUSampler(pid)
+//	ticker := jobticker.Start("cpu", func() error {
+//	    _, err := sampler.Sample()
+//	    return err
+//	}, 10*time.Second, log)
+//
+// and read with Last from anywhere else. A CPUSampler is safe for concurrent use.
+type CPUSampler struct {
+	pids []int
+
+	mu        sync.Mutex
+	sampledAt time.Time
+	host      *CPUStat
+	processes map[int]time.Duration
+	last      *CPUUsage
+}
+
+// NewCPUSampler creates a sampler of the host CPU and of the given processes.
+func NewCPUSampler(pids ...int) *CPUSampler {
+	return &CPUSampler{pids: pids}
+}
+
+// Sample reads the current counters and returns the utilisation since the
+// previous call. The first call only records the counters and returns an
+// empty CPUUsage with zero Window.
+func (s *CPUSampler) Sample() (*CPUUsage, error) {
+	host, err := ReadCPUStat()
+	if err != nil {
+		return nil, err
+	}
+
+	load, err := ReadLoadAverage()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make(map[int]time.Duration, len(s.pids))
+
+	for _, pid := range s.pids {
+		info, err := ReadProcess(pid)
+		if err != nil {
+			continue
+		}
+
+		processes[pid] = info.CPUTime()
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := &CPUUsage{Load: *load, Processes: make(map[int]float64, len(processes))}
+
+	if s.host != nil {
+		usage.Window = now.Sub(s.sampledAt)
+		usage.Total = cpuPercent(s.host.Total, host.Total)
+		usage.Cores = make([]float64, len(host.Cores))
+
+		for i := range host.Cores {
+			if i < len(s.host.Cores) {
+				usage.Cores[i] = cpuPercent(s.host.Cores[i], host.Cores[i])
+			}
+		}
+
+		for pid, cpu := range processes {
+			prev, ok := s.processes[pid]
+			if !ok || usage.Window <= 0 {
+				continue
+			}
+
+			usage.Processes[pid] = float64(cpu-prev) / float64(usage.Window) * 100
+		}
+
+		s.last = usage
+	}
+
+	s.sampledAt = now
+	s.host = host
+	s.processes = processes
+
+	return usage, nil
+}
+
+// Last returns the result of the latest Sample call that had a previous
+// sample to compare with, or nil if there is none yet.
+func (s *CPUSampler) Last() *CPUUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.last
+}
+
+// parseCPUTimes parses the tick counters of a /proc/stat "cpu" line.
+func parseCPUTimes(fields []string) (CPUTimes, error) {
+	ticks := make([]uint64, 8)
+
+	for i := 0; i < len(ticks) && i < len(fields); i++ {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return CPUTimes{}, err
+		}
+
+		ticks[i] = value
+	}
+
+	return CPUTimes{
+		User:    ticksToDuration(ticks[0]),
+		Nice:    ticksToDuration(ticks[1]),
+		System:  ticksToDuration(ticks[2]),
+		Idle:    ticksToDuration(ticks[3]),
+		IOWait:  ticksToDuration(ticks[4]),
+		IRQ:     ticksToDuration(ticks[5]),
+		SoftIRQ: ticksToDuration(ticks[6]),
+		Steal:   ticksToDuration(ticks[7]),
+	}, nil
+}
+
+// cpuPercent returns the busy share of the time elapsed between two samples.
+func cpuPercent(prev, cur CPUTimes) float64 {
+	total := cur.Total() - prev.Total()
+	if total <= 0 {
+		return 0
+	}
+
+	busy := max(cur.Busy()-prev.Busy(), 0)
+
+	return min(float64(busy)/float64(total)*100, 100)
+}
@@ -0,0 +1,111 @@
+package bash
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCPUStat(t *testing.T) {
+	t.Run("current host", func(t *testing.T) {
+		stat, err := ReadCPUStat()
+		require.NoError(t, err)
+
+		assert.Positive(t, stat.Total.Total())
+		assert.NotEmpty(t, stat.Cores)
+	})
+
+	t.Run("fake stat", func(t *testing.T) {
+		root := t.TempDir()
+		content := "cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 50 0 50 350 50 0 0 0 0 0\ncpu1 50 0 50 350 50 0 0 0 0 0\nbtime 1\n"
+		require.NoError(t, os.WriteFile(filepath.Join(root, "stat"), []byte(content), 0o644))
+
+		defer func(path string) { procPath = path }(procPath)
+		procPath = root
+
+		stat, err := ReadCPUStat()
+		require.NoError(t, err)
+
+		assert.Len(t, stat.Cores, 2)
+		assert.Equal(t, 10*time.Second, stat.Total.Total())
+		assert.Equal(t, 2*time.Second, stat.Total.Busy())
+	})
+}
+
+func TestReadLoadAverage(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "loadavg"), []byte("0.50 1.25 2.00 1/100 4242\n"), 0o644))
+
+	defer func(path string) { procPath = path }(procPath)
+	procPath = root
+
+	load, err := ReadLoadAverage()
+	require.NoError(t, err)
+	assert.Equal(t, &LoadAverage{Load1: 0.5, Load5: 1.25, Load15: 2}, load)
+}
+
+func TestSampleCPU(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+
+		// Keep the current process busy to have something to measure.
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+
+		usage, err := SampleCPU(context.Background(), 300*time.Millisecond, os.Getpid(), 1<<30)
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, usage.Window, 300*time.Millisecond)
+		assert.NotEmpty(t, usage.Cores)
+		assert.Greater(t, usage.Total, 0.0)
+		assert.LessOrEqual(t, usage.Total, 100.0)
+		assert.Greater(t, usage.Processes[os.Getpid()], 10.0)
+		assert.NotContains(t, usage.Processes, 1<<30)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := SampleCPU(ctx, time.Minute)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestCPUSampler(t *testing.T) {
+	sampler := NewCPUSampler()
+	assert.Nil(t, sampler.Last())
+
+	usage, err := sampler.Sample()
+	require.NoError(t, err)
+	assert.Zero(t, usage.Window)
+	assert.Nil(t, sampler.Last())
+
+	time.Sleep(50 * time.Millisecond)
+
+	usage, err = sampler.Sample()
+	require.NoError(t, err)
+	assert.Positive(t, usage.Window)
+	assert.Same(t, usage, sampler.Last())
+}
+
+func TestCPUPercent(t *testing.T) {
+	prev := CPUTimes{User: time.Second, Idle: 9 * time.Second}
+	cur := CPUTimes{User: 4 * time.Second, Idle: 10 * time.Second}
+
+	assert.InDelta(t, 75.0, cpuPercent(prev, cur), 0.001)
+	assert.Zero(t, cpuPercent(cur, cur))
+}
@@ -0,0 +1,366 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// duBlockSize is the unit of fileStat.blocks.
+const duBlockSize = 512
+
+// fileStat holds the stat(2) fields DirSize needs.
+type fileStat struct {
+	dev    uint64
+	ino    uint64
+	nlink  uint64
+	blocks int64
+}
+
+// DiskUsage holds the statistics of a mounted filesystem in bytes, as
+// reported by statfs(2).
+type DiskUsage struct {
+	// Path is the path the statistics were read for.
+	Path string
+
+	// Mount is the mount the statistics belong to. It is set by
+	// ReadMountsUsage only.
+	Mount *Mount
+
+	Total uint64
+	Free  uint64
+
+	// Available is the free space available to unprivileged users. It is
+	// smaller than Free by the blocks reserved for root.
+	Available uint64
+
+	Used uint64
+
+	Inodes     uint64
+	InodesFree uint64
+	InodesUsed uint64
+}
+
+// UsedPercent returns the used space as a percentage of the space available
+// to unprivileged users, calculated the same way as the Use% column of `df`.
+func (d *DiskUsage) UsedPercent() float64 {
+	if d.Used+d.Available == 0 {
+		return 0
+	}
+
+	return float64(d.Used) / float64(d.Used+d.Available) * 100
+}
+
+// InodesPercent returns the used inodes as a percentage of all inodes.
+// Filesystems without a fixed inode table report 0.
+func (d *DiskUsage) InodesPercent() float64 {
+	if d.Inodes == 0 {
+		return 0
+	}
+
+	return float64(d.InodesUsed) / float64(d.Inodes) * 100
+}
+
+// Mount describes a line of /proc/self/mountinfo. See proc(5) for the
+// meaning of the fields.
+type Mount struct {
+	ID       int
+	ParentID int
+	Major    int
+	Minor    int
+
+	// Root is the path of the directory in the filesystem that forms the
+	// root of this mount, e.g. "/" or the source directory of a bind mount.
+	Root string
+
+	// MountPoint is the path of the mount point.
+	MountPoint string
+
+	// Options are the per-mount options like "rw" or "noatime".
+	Options []string
+
+	// FSType is the filesystem type like "ext4" or "tmpfs".
+	FSType string
+
+	// Source is the filesystem specific source like "/dev/sda1".
+	Source string
+
+	// SuperOptions are the per-superblock options.
+	SuperOptions []string
+}
+
+// ReadDiskUsage returns the statistics of the filesystem containing path.
+// It is supported on Linux and macOS, elsewhere the error wraps
+// errors.ErrUnsupported.
+//
+// Example:
+//
+//	usage, err := ReadDiskUsage("/var/lib/postgresql")
+//	if err != nil {
+//	    return err
+//	}
+//	if usage.UsedPercent() > 90 {
+//	    // less than 10% of the disk is left
+//	}
+func ReadDiskUsage(path string) (*DiskUsage, error) {
+	usage, err := statfs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Used = usage.Total - min(usage.Free, usage.Total)
+	usage.InodesUsed = usage.Inodes - min(usage.InodesFree, usage.Inodes)
+
+	return usage, nil
+}
+
+// Mounts parses /proc/self/mountinfo and returns the mounts visible to the
+// current process in mount order.
+func Mounts() ([]*Mount, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "self", "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMountinfo(string(content))
+}
+
+// ReadMountsUsage returns the statistics of every mounted filesystem with a
+// non-zero size, like `df` does without -a. Pseudo filesystems such as proc
+// or sysfs report zero size and are skipped, as are mount points the process
+// is not allowed to access. When the same mount point is mounted several
+// times only the topmost mount is reported.
+func ReadMountsUsage() ([]*DiskUsage, error) {
+	mounts, err := Mounts()
+	if err != nil {
+		return nil, err
+	}
+
+	// Later mounts hide earlier ones on the same mount point.
+	top := make(map[string]*Mount, len(mounts))
+	for _, mount := range mounts {
+		top[mount.MountPoint] = mount
+	}
+
+	list := make([]*DiskUsage, 0, len(top))
+
+	for _, mount := range mounts {
+		if top[mount.MountPoint] != mount {
+			continue
+		}
+
+		usage, err := ReadDiskUsage(mount.MountPoint)
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if usage.Total == 0 {
+			continue
+		}
+
+		usage.Mount = mount
+		list = append(list, usage)
+	}
+
+	return list, nil
+}
+
+// DirUsage is the result of DirSize.
+type DirUsage struct {
+	// Size is the sum of the apparent file sizes, like `du -sb` reports it.
+	Size int64
+
+	// Disk is the space allocated on disk, like `du -s` reports it.
+	Disk int64
+
+	// Files is the number of non-directory entries, hard links counted once.
+	Files int
+
+	// Dirs is the number of directories including the root.
+	Dirs int
+}
+
+// DirSize calculates the size of the tree under path like `du -s` does.
+//
+// Behavior:
+//   - Files with several hard links inside the tree are counted once
+//   - Symbolic links are not followed, the size of the link itself is counted
+//   - Files and directories vanishing during the walk are ignored
+//   - Returns ctx.Err() as soon as ctx is done
+//   - Supported on Linux and macOS, elsewhere the error wraps
+//     errors.ErrUnsupported
+func DirSize(ctx context.Context, path string) (*DirUsage, error) {
+	type inode struct {
+		dev uint64
+		ino uint64
+	}
+
+	var (
+		usage DirUsage
+		seen  = make(map[inode]struct{})
+	)
+
+	err := filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && name != path {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		stat, err := statBlocks(name, info)
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && stat.nlink > 1 {
+			key := inode{dev: stat.dev, ino: stat.ino}
+			if _, ok := seen[key]; ok {
+				return nil
+			}
+
+			seen[key] = struct{}{}
+		}
+
+		if entry.IsDir() {
+			usage.Dirs++
+		} else {
+			usage.Files++
+		}
+
+		usage.Size += info.Size()
+		usage.Disk += stat.blocks * duBlockSize
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// parseMountinfo parses the content of a mountinfo file.
+func parseMountinfo(content string) ([]*Mount, error) {
+	mounts := make([]*Mount, 0)
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		mount, err := parseMountinfoLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		mounts = append(mounts, mount)
+	}
+
+	return mounts, nil
+}
+
+// parseMountinfoLine parses a line like
+// "36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue".
+func parseMountinfoLine(line string) (*Mount, error) {
+	// The optional fields are terminated by a single hyphen. The fields after
+	// it are split on single spaces because the source may be empty.
+	head, tail, ok := strings.Cut(line, " - ")
+	fields := strings.Fields(head)
+	super := strings.Split(tail, " ")
+
+	if !ok || len(fields) < 6 || len(super) < 2 {
+		return nil, fmt.Errorf("%w: mountinfo line %q", ErrInvalidProcStat, line)
+	}
+
+	id, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: mount id: %w", ErrInvalidProcStat, err)
+	}
+
+	parent, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: parent id: %w", ErrInvalidProcStat, err)
+	}
+
+	majorValue, minorValue, ok := strings.Cut(fields[2], ":")
+	if !ok {
+		return nil, fmt.Errorf("%w: device %q", ErrInvalidProcStat, fields[2])
+	}
+
+	major, err := strconv.Atoi(majorValue)
+	if err != nil {
+		return nil, fmt.Errorf("%w: device major: %w", ErrInvalidProcStat, err)
+	}
+
+	minor, err := strconv.Atoi(minorValue)
+	if err != nil {
+		return nil, fmt.Errorf("%w: device minor: %w", ErrInvalidProcStat, err)
+	}
+
+	mount := &Mount{
+		ID:         id,
+		ParentID:   parent,
+		Major:      major,
+		Minor:      minor,
+		Root:       unescapeMountinfo(fields[3]),
+		MountPoint: unescapeMountinfo(fields[4]),
+		Options:    strings.Split(fields[5], ","),
+		FSType:     super[0],
+		Source:     unescapeMountinfo(super[1]),
+	}
+
+	if len(super) > 2 && super[2] != "" {
+		mount.SuperOptions = strings.Split(super[2], ",")
+	}
+
+	return mount, nil
+}
+
+// unescapeMountinfo decodes the octal escapes the kernel uses for spaces,
+// tabs, newlines and backslashes in mountinfo paths, e.g. "\040" for " ".
+func unescapeMountinfo(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var result strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+4 <= len(value) {
+			if code, err := strconv.ParseUint(value[i+1:i+4], 8, 8); err == nil {
+				result.WriteByte(byte(code))
+				i += 3
+
+				continue
+			}
+		}
+
+		result.WriteByte(value[i])
+	}
+
+	return result.String()
+}
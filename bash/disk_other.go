@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package bash
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// statfs reports that the layout of statfs(2) is not known on this platform.
+func statfs(path string) (*DiskUsage, error) {
+	return nil, &fs.PathError{Op: "statfs", Path: path, Err: errors.ErrUnsupported}
+}
+
+// statBlocks reports that the layout of stat(2) is not known on this
+// platform.
+func statBlocks(name string, _ fs.FileInfo) (*fileStat, error) {
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin
+
+package bash
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// statfs reads the space and inode counters of the filesystem containing
+// path with statfs(2).
+func statfs(path string) (*DiskUsage, error) {
+	var stat syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, &fs.PathError{Op: "statfs", Path: path, Err: err}
+	}
+
+	size := uint64(stat.Bsize) //nolint:gosec // block size is never negative
+
+	return &DiskUsage{
+		Path:       path,
+		Total:      stat.Blocks * size,
+		Free:       stat.Bfree * size,
+		Available:  stat.Bavail * size,
+		Inodes:     stat.Files,
+		InodesFree: stat.Ffree,
+	}, nil
+}
+
+// statBlocks returns the device, inode, link count and allocated blocks of
+// the file name described by info.
+func statBlocks(name string, info fs.FileInfo) (*fileStat, error) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("stat %s: unsupported platform", name)
+	}
+
+	return &fileStat{
+		dev:    uint64(stat.Dev), //nolint:gosec,unconvert // Dev is not uint64 everywhere
+		ino:    stat.Ino,
+		nlink:  uint64(stat.Nlink), //nolint:unconvert // Nlink is not uint64 everywhere
+		blocks: stat.Blocks,
+	}, nil
+}
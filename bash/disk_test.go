@@ -0,0 +1,146 @@
+package bash
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMountinfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+24 22 0:22 / /sys rw,nosuid shared:7 master:1 - sysfs sysfs rw
+25 22 8:2 /data /mnt/my\040disk rw - xfs /dev/sdb1 rw,attr2
+26 22 0:45 / /run/empty rw - tmpfs 
+`
+
+func TestReadDiskUsage(t *testing.T) {
+	t.Run("existing path", func(t *testing.T) {
+		usage, err := ReadDiskUsage(t.TempDir())
+		require.NoError(t, err)
+
+		assert.Positive(t, usage.Total)
+		assert.LessOrEqual(t, usage.Available, usage.Free)
+		assert.Equal(t, usage.Total, usage.Used+usage.Free)
+		assert.InDelta(t, 50, usage.UsedPercent(), 50)
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		_, err := ReadDiskUsage("/nonexistent/disk/path")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("percentages", func(t *testing.T) {
+		usage := &DiskUsage{Used: 30, Available: 70, Inodes: 200, InodesUsed: 50}
+		assert.InDelta(t, 30, usage.UsedPercent(), 0.001)
+		assert.InDelta(t, 25, usage.InodesPercent(), 0.001)
+
+		empty := &DiskUsage{}
+		assert.Zero(t, empty.UsedPercent())
+		assert.Zero(t, empty.InodesPercent())
+	})
+}
+
+func TestParseMountinfo(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		mounts, err := parseMountinfo(testMountinfo)
+		require.NoError(t, err)
+		require.Len(t, mounts, 5)
+
+		assert.Equal(t, &Mount{
+			ID:           22,
+			ParentID:     1,
+			Major:        8,
+			Minor:        1,
+			Root:         "/",
+			MountPoint:   "/",
+			Options:      []string{"rw", "relatime"},
+			FSType:       "ext4",
+			Source:       "/dev/sda1",
+			SuperOptions: []string{"rw", "errors=remount-ro"},
+		}, mounts[0])
+
+		assert.Equal(t, "sysfs", mounts[2].FSType)
+		assert.Equal(t, "/data", mounts[3].Root)
+		assert.Equal(t, "/mnt/my disk", mounts[3].MountPoint)
+		assert.Equal(t, "tmpfs", mounts[4].FSType)
+		assert.Nil(t, mounts[4].SuperOptions)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseMountinfo("22 1 8:1 / / rw shared:1 ext4 /dev/sda1 rw\n")
+		assert.ErrorIs(t, err, ErrInvalidProcStat)
+
+		_, err = parseMountinfo("x 1 8:1 / / rw - ext4 /dev/sda1 rw\n")
+		assert.ErrorIs(t, err, ErrInvalidProcStat)
+
+		_, err = parseMountinfo("22 1 8 / / rw - ext4 /dev/sda1 rw\n")
+		assert.ErrorIs(t, err, ErrInvalidProcStat)
+	})
+
+	t.Run("escapes", func(t *testing.T) {
+		assert.Equal(t, "a b\tc\\d", unescapeMountinfo(`a\040b\011c\134d`))
+		assert.Equal(t, `a\04`, unescapeMountinfo(`a\04`))
+		assert.Equal(t, `a\xyzb`, unescapeMountinfo(`a\xyzb`))
+	})
+}
+
+func TestMounts(t *testing.T) {
+	mounts, err := Mounts()
+	require.NoError(t, err)
+	assert.NotEmpty(t, mounts)
+
+	usages, err := ReadMountsUsage()
+	require.NoError(t, err)
+
+	for _, usage := range usages {
+		assert.NotNil(t, usage.Mount)
+		assert.Equal(t, usage.Mount.MountPoint, usage.Path)
+		assert.Positive(t, usage.Total)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub", "deep"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a"), []byte(strings.Repeat("x", 1000)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "b"), []byte(strings.Repeat("x", 3000)), 0o644))
+	require.NoError(t, os.Link(filepath.Join(root, "sub", "b"), filepath.Join(root, "sub", "deep", "b-link")))
+	require.NoError(t, os.Symlink("a", filepath.Join(root, "sub", "a-symlink")))
+
+	t.Run("hard links counted once", func(t *testing.T) {
+		usage, err := DirSize(context.Background(), root)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, usage.Dirs)
+		assert.Equal(t, 3, usage.Files)
+
+		dirs := int64(0)
+		for _, dir := range []string{root, filepath.Join(root, "sub"), filepath.Join(root, "sub", "deep")} {
+			info, err := os.Stat(dir)
+			require.NoError(t, err)
+			dirs += info.Size()
+		}
+
+		assert.Equal(t, dirs+1000+3000+int64(len("a")), usage.Size)
+		assert.Positive(t, usage.Disk)
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		_, err := DirSize(context.Background(), filepath.Join(root, "missing"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := DirSize(ctx, root)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
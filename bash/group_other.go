@@ -0,0 +1,55 @@
+//go:build !unix
+
+package bash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup does nothing, process groups are not supported on this
+// platform. Cancelling cmd kills only cmd itself, as exec.CommandContext
+// does by default.
+func setProcessGroup(_, _ *exec.Cmd) {}
+
+// signalGroup kills the process pid on SIGKILL, its descendants are not
+// reached. Other signals cannot be sent on this platform.
+func signalGroup(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return nil
+	}
+
+	err := signalProcess(pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+
+	return err
+}
+
+// signalProcess kills the process pid on SIGKILL and returns syscall.ESRCH
+// if it has already exited. Other signals cannot be sent on this platform.
+func signalProcess(pid int, sig syscall.Signal) error {
+	if sig != syscall.SIGKILL {
+		return fmt.Errorf("signal %d: %w", int(sig), errors.ErrUnsupported)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer process.Release()
+
+	if err := process.Kill(); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return syscall.ESRCH
+		}
+
+		return err
+	}
+
+	return nil
+}
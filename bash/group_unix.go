@@ -0,0 +1,46 @@
+//go:build unix
+
+package bash
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd start in the process group of the started
+// leader, or in a new group led by cmd when leader is nil. Cancelling cmd
+// kills the whole group.
+func setProcessGroup(cmd, leader *exec.Cmd) {
+	pgid := 0
+
+	if leader == nil {
+		leader = cmd
+	} else {
+		pgid = leader.Process.Pid
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: pgid}
+	cmd.Cancel = func() error {
+		return killGroup(leader, syscall.SIGKILL)
+	}
+}
+
+// signalGroup sends sig to the process group led by pid.
+func signalGroup(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return nil
+	}
+
+	err := syscall.Kill(-pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+
+	return err
+}
+
+// signalProcess sends sig to the process pid.
+func signalProcess(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
@@ -0,0 +1,179 @@
+package bash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SystemMemory holds the host memory statistics from /proc/meminfo in bytes.
+// The fields follow the columns of the `free` command.
+type SystemMemory struct {
+	Total     uint64
+	Used      uint64
+	Free      uint64
+	Available uint64
+	Shared    uint64
+	Buffers   uint64
+
+	// Cached includes reclaimable slab memory, as `free` reports it.
+	Cached uint64
+
+	SwapTotal uint64
+	SwapUsed  uint64
+	SwapFree  uint64
+}
+
+// ReadSystemMemory parses /proc/meminfo.
+//
+// Used memory is calculated as Total - Available the same way modern `free`
+// does. On kernels without MemAvailable it falls back to
+// Total - Free - Buffers - Cached.
+//
+// Example:
+//
+//	mem, err := ReadSystemMemory()
+//	if err != nil {
+//	    return err
+//	}
+//	if float64(mem.Available)/float64(mem.Total) < 0.1 {
+//	    // less than 10% of memory is available
+//	}
+func ReadSystemMemory() (*SystemMemory, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+
+		number, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse meminfo %s: %w", key, err)
+		}
+
+		// Values are reported in kibibytes except for page counters.
+		if len(fields) > 1 && fields[1] == "kB" {
+			number *= 1024
+		}
+
+		values[key] = number
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return nil, ErrInvalidProcStat
+	}
+
+	mem := &SystemMemory{
+		Total:     total,
+		Free:      values["MemFree"],
+		Shared:    values["Shmem"],
+		Buffers:   values["Buffers"],
+		Cached:    values["Cached"] + values["SReclaimable"],
+		SwapTotal: values["SwapTotal"],
+		SwapFree:  values["SwapFree"],
+	}
+
+	if available, ok := values["MemAvailable"]; ok {
+		mem.Available = min(available, total)
+	} else {
+		mem.Available = min(mem.Free+mem.Buffers+mem.Cached, total)
+	}
+
+	mem.Used = total - mem.Available
+	mem.SwapUsed = mem.SwapTotal - min(mem.SwapFree, mem.SwapTotal)
+
+	return mem, nil
+}
+
+// ProcessCPUPercent returns the CPU usage of a process averaged over its
+// whole lifetime, including the CPU time of its waited-for children, like
+// `ps S -o pcpu` reports it. 100 means one fully used core.
+func ProcessCPUPercent(pid int) (float64, error) {
+	info, err := ReadProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	uptime := info.Uptime()
+	if uptime <= 0 {
+		return 0, nil
+	}
+
+	cpu := info.CPUTime() + info.ChildrenUserTime + info.ChildrenSystemTime
+
+	return float64(cpu) / float64(uptime) * 100, nil
+}
+
+// ProcessMemPercent returns the resident memory of a process as a percentage
+// of the total host memory, like `ps -o pmem` reports it.
+func ProcessMemPercent(pid int) (float64, error) {
+	info, err := ReadProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	mem, err := ReadSystemMemory()
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(info.RSS) / float64(mem.Total) * 100, nil
+}
+
+// ProcessMemUsed returns the resident memory of a process in bytes.
+func ProcessMemUsed(pid int) (uint64, error) {
+	info, err := ReadProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	return info.RSS, nil
+}
+
+// formatPercent formats a percentage with one decimal place and "%" suffix.
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', 1, 64) + "%"
+}
+
+// formatMegabytes formats bytes as binary megabytes with " MB" suffix.
+func formatMegabytes(bytes uint64, precision int) string {
+	return strconv.FormatFloat(float64(bytes)/1024/1024, 'f', precision, 64) + " MB"
+}
+
+// formatHumanSize formats bytes the way `ls -h` does, e.g. "512", "4.0K", "12M".
+func formatHumanSize(size int64) string {
+	const units = "KMGTPE"
+
+	if size < 1024 {
+		return strconv.FormatInt(size, 10)
+	}
+
+	value := float64(size)
+	unit := -1
+
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if value < 10 {
+		return strconv.FormatFloat(value, 'f', 1, 64) + string(units[unit])
+	}
+
+	return strconv.FormatFloat(value, 'f', 0, 64) + string(units[unit])
+}
@@ -0,0 +1,116 @@
+package bash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMeminfo = `MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    5000000 kB
+Buffers:          200000 kB
+Cached:          3000000 kB
+SwapCached:            0 kB
+Shmem:             10000 kB
+SReclaimable:     100000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+HugePages_Total:       0
+`
+
+func TestReadSystemMemory(t *testing.T) {
+	t.Run("current host", func(t *testing.T) {
+		mem, err := ReadSystemMemory()
+		require.NoError(t, err)
+
+		assert.Positive(t, mem.Total)
+		assert.LessOrEqual(t, mem.Used, mem.Total)
+		assert.LessOrEqual(t, mem.Available, mem.Total)
+	})
+
+	t.Run("fake meminfo", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "meminfo"), []byte(testMeminfo), 0o644))
+
+		defer func(path string) { procPath = path }(procPath)
+		procPath = root
+
+		mem, err := ReadSystemMemory()
+		require.NoError(t, err)
+
+		assert.Equal(t, &SystemMemory{
+			Total:     8000000 * 1024,
+			Used:      3000000 * 1024,
+			Free:      1000000 * 1024,
+			Available: 5000000 * 1024,
+			Shared:    10000 * 1024,
+			Buffers:   200000 * 1024,
+			Cached:    3100000 * 1024,
+			SwapTotal: 2000000 * 1024,
+			SwapUsed:  500000 * 1024,
+			SwapFree:  1500000 * 1024,
+		}, mem)
+
+		used, err := MemUsed()
+		require.NoError(t, err)
+		assert.Equal(t, "2930 MB", used)
+
+		total, err := MemAvail()
+		require.NoError(t, err)
+		assert.Equal(t, "7812 MB", total)
+	})
+
+	t.Run("invalid meminfo", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "meminfo"), []byte("Foo: 1 kB\n"), 0o644))
+
+		defer func(path string) { procPath = path }(procPath)
+		procPath = root
+
+		_, err := ReadSystemMemory()
+		assert.ErrorIs(t, err, ErrInvalidProcStat)
+
+		used, err := MemUsed()
+		assert.Error(t, err)
+		assert.Equal(t, "0.0 MB", used)
+	})
+}
+
+func TestProcessMetrics(t *testing.T) {
+	t.Run("current process", func(t *testing.T) {
+		used, err := ProcessMemUsed(os.Getpid())
+		require.NoError(t, err)
+		assert.Positive(t, used)
+
+		percent, err := ProcessMemPercent(os.Getpid())
+		require.NoError(t, err)
+		assert.Positive(t, percent)
+		assert.Less(t, percent, 100.0)
+
+		cpu, err := ProcessCPUPercent(os.Getpid())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, cpu, 0.0)
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		_, err := ProcessMemUsed(1 << 30)
+		assert.ErrorIs(t, err, ErrNotRunning)
+
+		_, err = ProcessCPUPercent(1 << 30)
+		assert.ErrorIs(t, err, ErrNotRunning)
+	})
+}
+
+func TestFormatters(t *testing.T) {
+	assert.Equal(t, "25.5%", formatPercent(25.46))
+	assert.Equal(t, "1.5 MB", formatMegabytes(1536*1024, 1))
+	assert.Equal(t, "2 MB", formatMegabytes(1536*1024, 0))
+	assert.Equal(t, "512", formatHumanSize(512))
+	assert.Equal(t, "4.0K", formatHumanSize(4096))
+	assert.Equal(t, "1.5M", formatHumanSize(1536*1024))
+	assert.Equal(t, "12G", formatHumanSize(12<<30))
+}
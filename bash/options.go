@@ -0,0 +1,99 @@
+package bash
+
+import (
+	"regexp"
+	"time"
+)
+
+// Option configures a Runner.
+type Option func(r *Runner)
+
+// SupervisorOption configures a Supervisor.
+type SupervisorOption func(s *Supervisor)
+
+// WithTimeout limits the run time of every command started by the Runner.
+// The deadline is applied on top of the context passed to Run.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Runner) {
+		r.timeout = timeout
+	}
+}
+
+// WithWaitDelay sets how long a killed command may keep its output pipes open
+// (e.g. because of orphaned grandchildren) before Run gives up waiting.
+func WithWaitDelay(delay time.Duration) Option {
+	return func(r *Runner) {
+		r.waitDelay = delay
+	}
+}
+
+// WithDir sets the working directory of started commands.
+func WithDir(dir string) Option {
+	return func(r *Runner) {
+		r.dir = dir
+	}
+}
+
+// WithEnv sets the environment of started commands in "key=value" form.
+// The environment of the current process is used when env is nil.
+func WithEnv(env []string) Option {
+	return func(r *Runner) {
+		r.env = env
+	}
+}
+
+// WithStderrPolicy sets when output on stderr makes a command fail.
+// Non-zero exit codes are always failures.
+func WithStderrPolicy(policy StderrPolicy) Option {
+	return func(r *Runner) {
+		r.stderrPolicy = policy
+	}
+}
+
+// WithStderrPattern makes a command fail only when its stderr matches pattern.
+// It implies the StderrMatch policy.
+func WithStderrPattern(pattern *regexp.Regexp) Option {
+	return func(r *Runner) {
+		r.stderrPolicy = StderrMatch
+		r.stderrPattern = pattern
+	}
+}
+
+// WithStripANSI removes ANSI escape sequences from lines delivered by Stream.
+func WithStripANSI() Option {
+	return func(r *Runner) {
+		r.stripANSI = true
+	}
+}
+
+// WithRunner makes the Supervisor start processes with the working directory,
+// environment and ANSI stripping settings of r.
+func WithRunner(r *Runner) SupervisorOption {
+	return func(s *Supervisor) {
+		s.runner = r
+	}
+}
+
+// WithOutputHandler makes the Supervisor pass every stdout and stderr line of
+// the process to handler. Handler errors are logged and do not stop the process.
+// Output is discarded when no handler is set.
+func WithOutputHandler(handler LineHandler) SupervisorOption {
+	return func(s *Supervisor) {
+		s.output = handler
+	}
+}
+
+// WithBackoff sets the initial and the maximum restart delay.
+func WithBackoff(initial, maximum time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.minBackoff = initial
+		s.maxBackoff = max(initial, maximum)
+	}
+}
+
+// WithStopTimeout sets how long Stop waits after SIGTERM before sending SIGKILL.
+func WithStopTimeout(timeout time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.stopTimeout = timeout
+	}
+}
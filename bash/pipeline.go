@@ -0,0 +1,268 @@
+package bash
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command describes a single program invocation. Arguments are passed to the
+// program as is, without any shell interpretation.
+type Command struct {
+	Name string
+	Args []string
+}
+
+// Cmd creates a Command.
+//
+// Example:
+//
+//	Cmd("grep", "-e", userInput) // userInput is never interpreted by a shell
+func Cmd(name string, args ...string) *Command {
+	return &Command{Name: name, Args: args}
+}
+
+// String returns the command as a shell-quoted string. It is meant for logs
+// and for the rare cases when a shell string is unavoidable.
+func (c *Command) String() string {
+	words := make([]string, 0, len(c.Args)+1)
+	words = append(words, Quote(c.Name))
+
+	for _, arg := range c.Args {
+		words = append(words, Quote(arg))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Pipeline is a chain of commands where the standard output of every command
+// is connected to the standard input of the next one. The commands are wired
+// in Go, no shell is involved.
+type Pipeline struct {
+	Commands []*Command
+}
+
+// Pipe creates a Pipeline from the given commands.
+//
+// Example:
+//
+//	p := Pipe(Cmd("ps", "-ylp", pid), Cmd("awk", "{x += $8} END {print x}"))
+//	out, err := ExecutePipeline(ctx, p)
+func Pipe(commands ...*Command) *Pipeline {
+	return &Pipeline{Commands: commands}
+}
+
+// String returns the pipeline as a shell-quoted string, e.g. "ls -l | grep '.go'".
+func (p *Pipeline) String() string {
+	parts := make([]string, 0, len(p.Commands))
+
+	for _, command := range p.Commands {
+		parts = append(parts, command.String())
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+// RunPipeline executes all commands of the pipeline concurrently and waits
+// for them to finish. The commands share one process group, so cancelling
+// ctx kills the whole pipeline.
+//
+// Returns:
+//   - *Result: Stdout of the last command, stderr of all commands and the exit
+//     status of the last command, as a shell without pipefail reports it.
+//   - error: Same as Run, reported for the last command of the pipeline.
+//
+// Example:
+//
+//	res, err := NewRunner().RunPipeline(ctx, Pipe(
+//	    Cmd("ls", "-hSRs", "--", path),
+//	    Cmd("grep", "-E", "-e", mask),
+//	    Cmd("head", "-n", "20"),
+//	))
+func (r *Runner) RunPipeline(ctx context.Context, p *Pipeline) (*Result, error) {
+	if p == nil || len(p.Commands) == 0 {
+		return nil, ErrInvalidCommand
+	}
+
+	for _, command := range p.Commands {
+		if command == nil || command.Name == "" {
+			return nil, ErrInvalidCommand
+		}
+	}
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	var stdout bytes.Buffer
+
+	stderr := &syncBuffer{}
+
+	cmds := make([]*exec.Cmd, len(p.Commands))
+	for i, command := range p.Commands {
+		cmds[i] = r.command(ctx, command.Name, command.Args...)
+		cmds[i].Stderr = stderr
+	}
+
+	cmds[len(cmds)-1].Stdout = &stdout
+
+	// Parent copies of the pipe ends must be closed once the children have
+	// them, otherwise readers never see EOF and writers never get SIGPIPE.
+	pipes, err := connect(cmds)
+	defer closeAll(pipes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	startedAt := time.Now()
+
+	if err := startGroup(cmds); err != nil {
+		return nil, err
+	}
+
+	closeAll(pipes)
+
+	var lastErr error
+
+	for i, cmd := range cmds {
+		err := cmd.Wait()
+		if i == len(cmds)-1 {
+			lastErr = err
+		}
+	}
+
+	last := cmds[len(cmds)-1]
+
+	res := &Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(startedAt),
+	}
+
+	res.ExitCode, res.Signal = exitStatus(last)
+
+	return res, r.check(ctx, res, lastErr, p.Commands[len(p.Commands)-1])
+}
+
+// ExecutePipeline runs a pipeline with the default Runner and returns the
+// standard output of its last command.
+func ExecutePipeline(ctx context.Context, p *Pipeline) (string, error) {
+	res, err := defaultRunner.RunPipeline(ctx, p)
+	if res == nil {
+		return "", err
+	}
+
+	return res.Stdout, err
+}
+
+// Quote returns s quoted for safe use as a single word in a POSIX shell
+// command line. Prefer Cmd and Pipe, which need no quoting at all.
+//
+// Example:
+//
+//	Quote("it's") // Returns 'it'\''s'.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	if strings.IndexFunc(s, isUnsafeShellRune) < 0 {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isUnsafeShellRune reports whether r has to be quoted in a shell word.
+func isUnsafeShellRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case strings.ContainsRune("@%+=:,./-_", r):
+		return false
+	default:
+		return true
+	}
+}
+
+// connect wires stdout of every command to stdin of the next one and returns
+// the parent copies of the created pipe ends.
+func connect(cmds []*exec.Cmd) ([]io.Closer, error) {
+	pipes := make([]io.Closer, 0, 2*(len(cmds)-1))
+
+	for i := 0; i < len(cmds)-1; i++ {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return pipes, err
+		}
+
+		pipes = append(pipes, reader, writer)
+
+		cmds[i].Stdout = writer
+		cmds[i+1].Stdin = reader
+	}
+
+	return pipes, nil
+}
+
+// startGroup starts the commands one by one placing all of them into the
+// process group of the first one. If a command fails to start, the already
+// started ones are killed and reaped.
+func startGroup(cmds []*exec.Cmd) error {
+	for i, cmd := range cmds {
+		if i > 0 {
+			setProcessGroup(cmd, cmds[0])
+		}
+
+		if err := cmd.Start(); err != nil {
+			for _, started := range cmds[:i] {
+				_ = started.Cancel()
+			}
+
+			for _, started := range cmds[:i] {
+				_ = started.Wait()
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// closeAll closes every closer ignoring errors. Closing twice is harmless.
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		_ = closer.Close()
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from several
+// commands of a pipeline.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
@@ -0,0 +1,94 @@
+package bash
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPipeline(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		res, err := NewRunner().RunPipeline(context.Background(), Pipe(
+			Cmd("printf", "b\\na\\nc\\n"),
+			Cmd("sort"),
+			Cmd("head", "-n", "2"),
+		))
+		require.NoError(t, err)
+
+		assert.Equal(t, "a\nb\n", res.Stdout)
+		assert.Equal(t, 0, res.ExitCode)
+	})
+
+	t.Run("early exit of reader", func(t *testing.T) {
+		out, err := ExecutePipeline(context.Background(), Pipe(Cmd("yes"), Cmd("head", "-n", "1")))
+		require.NoError(t, err)
+		assert.Equal(t, "y\n", out)
+	})
+
+	t.Run("exit code of last command", func(t *testing.T) {
+		res, err := NewRunner().RunPipeline(context.Background(), Pipe(Cmd("echo", "a"), Cmd("grep", "b")))
+		assert.ErrorIs(t, err, ErrBashExecuteFailed)
+		assert.Equal(t, 1, res.ExitCode)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := NewRunner().RunPipeline(ctx, Pipe(Cmd("sleep", "10"), Cmd("cat")))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("invalid pipeline", func(t *testing.T) {
+		_, err := NewRunner().RunPipeline(context.Background(), Pipe())
+		assert.ErrorIs(t, err, ErrInvalidCommand)
+
+		_, err = NewRunner().RunPipeline(context.Background(), Pipe(Cmd("echo"), Cmd("")))
+		assert.ErrorIs(t, err, ErrInvalidCommand)
+
+		_, err = NewRunner().RunPipeline(context.Background(), Pipe(Cmd("echo"), Cmd(NonExistentProcessName)))
+		assert.Error(t, err)
+	})
+}
+
+func TestPipelineString(t *testing.T) {
+	p := Pipe(Cmd("ls", "-l", "my dir"), Cmd("grep", "-e", "it's"))
+	assert.Equal(t, `ls -l 'my dir' | grep -e 'it'\''s'`, p.String())
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "''"},
+		{"simple", "simple"},
+		{"/var/log/app-1.log", "/var/log/app-1.log"},
+		{"two words", "'two words'"},
+		{"$(reboot)", "'$(reboot)'"},
+		{"it's", `'it'\''s'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Quote(tt.input))
+
+			out, err := Execute("bash", "-c", "printf %s "+Quote(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.input, out)
+		})
+	}
+}
+
+func TestGetLargeFileListInjection(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "injected")
+
+	_, _ = GetLargeFileList(".", "'; touch "+marker+"; echo '")
+	_, _ = GetLargeFileList("; touch "+marker, ".go")
+
+	assert.NoFileExists(t, marker)
+}
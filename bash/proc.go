@@ -0,0 +1,437 @@
+package bash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the kernel USER_HZ value used to express CPU times and
+// process start times in /proc/<pid>/stat. It is 100 on every Linux
+// architecture supported by Go.
+const clockTicks = 100
+
+// procPath is the mount point of the proc filesystem. It is a variable so
+// tests can point the inspector at a fake tree.
+var procPath = "/proc"
+
+// ProcessInfo describes a running process as reported by the proc filesystem.
+type ProcessInfo struct {
+	// PID is the process id.
+	PID int
+
+	// PPID is the parent process id.
+	PPID int
+
+	// UID is the real user id of the process owner.
+	UID int
+
+	// Name is the executable name (comm) truncated by the kernel to 15 bytes.
+	Name string
+
+	// Cmdline holds the process arguments. It is empty for kernel threads
+	// and zombies.
+	Cmdline []string
+
+	// State is the one letter process state (R, S, D, Z, T, ...).
+	State string
+
+	// StartTime is the wall clock time the process was started at.
+	StartTime time.Time
+
+	// RSS is the resident set size in bytes.
+	RSS uint64
+
+	// Threads is the number of threads in the process.
+	Threads int
+
+	// UserTime and SystemTime are the CPU times consumed by the process.
+	UserTime   time.Duration
+	SystemTime time.Duration
+
+	// ChildrenUserTime and ChildrenSystemTime are the CPU times consumed by
+	// the waited-for children of the process.
+	ChildrenUserTime   time.Duration
+	ChildrenSystemTime time.Duration
+}
+
+// CommandLine returns the process arguments joined with spaces, the same way
+// `ps -o args` and `pgrep -a` print them. Falls back to the process name for
+// kernel threads.
+func (p *ProcessInfo) CommandLine() string {
+	if len(p.Cmdline) == 0 {
+		return p.Name
+	}
+
+	return strings.Join(p.Cmdline, " ")
+}
+
+// Uptime returns the time elapsed since the process was started.
+func (p *ProcessInfo) Uptime() time.Duration {
+	return time.Since(p.StartTime)
+}
+
+// CPUTime returns the total CPU time consumed by the process itself.
+func (p *ProcessInfo) CPUTime() time.Duration {
+	return p.UserTime + p.SystemTime
+}
+
+// ReadProcess collects information about the process with the given PID
+// from /proc/<pid>/stat, status, cmdline and statm.
+//
+// Returns ErrNotRunning if the process does not exist.
+func ReadProcess(pid int) (*ProcessInfo, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPID, pid)
+	}
+
+	bootTime, err := readBootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	return readProcess(pid, bootTime)
+}
+
+// readProcess reads a single process using an already known boot time.
+func readProcess(pid int, bootTime time.Time) (*ProcessInfo, error) {
+	dir := filepath.Join(procPath, strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %d", ErrNotRunning, pid)
+		}
+
+		return nil, err
+	}
+
+	info := &ProcessInfo{PID: pid}
+
+	if err := parseProcStat(info, string(stat), bootTime); err != nil {
+		return nil, fmt.Errorf("parse stat of %d: %w", pid, err)
+	}
+
+	if err := readProcStatus(info, filepath.Join(dir, "status")); err != nil {
+		return nil, err
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	info.Cmdline = parseCmdline(cmdline)
+
+	statm, err := os.ReadFile(filepath.Join(dir, "statm"))
+	if err != nil {
+		return nil, err
+	}
+
+	if fields := strings.Fields(string(statm)); len(fields) > 1 {
+		pages, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse statm of %d: %w", pid, err)
+		}
+
+		info.RSS = pages * uint64(os.Getpagesize())
+	}
+
+	return info, nil
+}
+
+// Processes returns information about all processes visible in /proc ordered
+// by PID. Processes exiting while the list is being built are skipped.
+func Processes() ([]*ProcessInfo, error) {
+	pids, err := listPIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	bootTime, err := readBootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*ProcessInfo, 0, len(pids))
+
+	for _, pid := range pids {
+		info, err := readProcess(pid, bootTime)
+		if err != nil {
+			if errors.Is(err, ErrNotRunning) || errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		list = append(list, info)
+	}
+
+	return list, nil
+}
+
+// FindProcesses returns all running processes with the given name, matched
+// against the kernel process name (comm) or the base name of argv[0] the same
+// way pidof does. Zombies are skipped. Unlike PidofByProcess it returns every
+// match together with its full command line.
+func FindProcesses(process string) ([]*ProcessInfo, error) {
+	list, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*ProcessInfo, 0)
+
+	for _, info := range list {
+		if info.State == "Z" {
+			continue
+		}
+
+		if info.Name == process || (len(info.Cmdline) > 0 && filepath.Base(info.Cmdline[0]) == process) {
+			found = append(found, info)
+		}
+	}
+
+	return found, nil
+}
+
+// FindProcessesByParam returns all processes whose command line contains both
+// process and param. Command lines containing " bash " are skipped to ignore
+// shell wrappers, as PidofByProcessAndParam does, and so is the calling
+// process, the same way pgrep never lists itself.
+func FindProcessesByParam(process, param string) ([]*ProcessInfo, error) {
+	if process == "" || param == "" {
+		return nil, ErrInvalidCommand
+	}
+
+	list, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*ProcessInfo, 0)
+
+	self := os.Getpid()
+
+	for _, info := range list {
+		if len(info.Cmdline) == 0 || info.PID == self {
+			continue
+		}
+
+		line := info.CommandLine()
+
+		if strings.Contains(line, process) && !strings.Contains(line, " bash ") && strings.Contains(line, param) {
+			found = append(found, info)
+		}
+	}
+
+	return found, nil
+}
+
+// readProcessByPID reads a process with the PID passed as a string.
+func readProcessByPID(pid string) (*ProcessInfo, error) {
+	id, err := parsePID(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadProcess(id)
+}
+
+// listPIDs returns the sorted ids of all processes found in /proc.
+func listPIDs() ([]int, error) {
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]int, 0, len(entries))
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+
+		pids = append(pids, pid)
+	}
+
+	sort.Ints(pids)
+
+	return pids, nil
+}
+
+// parsePID converts a PID passed as a string to an integer.
+func parsePID(pid string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(pid))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPID, pid)
+	}
+
+	return id, nil
+}
+
+// parseProcStat fills info from the content of /proc/<pid>/stat.
+// The comm field is enclosed in parentheses and may itself contain spaces
+// and parentheses, so the remaining fields are located after the last ')'.
+func parseProcStat(info *ProcessInfo, stat string, bootTime time.Time) error {
+	open := strings.IndexByte(stat, '(')
+	closing := strings.LastIndexByte(stat, ')')
+
+	if open < 0 || closing < open {
+		return ErrInvalidProcStat
+	}
+
+	info.Name = stat[open+1 : closing]
+
+	// Fields are numbered as in proc(5) starting with state (3).
+	fields := strings.Fields(stat[closing+1:])
+	if len(fields) < 22 {
+		return ErrInvalidProcStat
+	}
+
+	field := func(n int) string { return fields[n-3] }
+
+	info.State = field(3)
+
+	ppid, err := strconv.Atoi(field(4))
+	if err != nil {
+		return err
+	}
+
+	info.PPID = ppid
+
+	ticks := make([]uint64, 0, 4)
+
+	for n := 14; n <= 17; n++ {
+		value, err := strconv.ParseInt(field(n), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		ticks = append(ticks, uint64(max(value, 0)))
+	}
+
+	info.UserTime = ticksToDuration(ticks[0])
+	info.SystemTime = ticksToDuration(ticks[1])
+	info.ChildrenUserTime = ticksToDuration(ticks[2])
+	info.ChildrenSystemTime = ticksToDuration(ticks[3])
+
+	threads, err := strconv.Atoi(field(20))
+	if err != nil {
+		return err
+	}
+
+	info.Threads = threads
+
+	start, err := strconv.ParseUint(field(22), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	info.StartTime = bootTime.Add(ticksToDuration(start))
+
+	return nil
+}
+
+// readProcStatus fills info from /proc/<pid>/status. Values from status take
+// precedence over stat because the name there is not ambiguous.
+func readProcStatus(info *ProcessInfo, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "Name":
+			info.Name = value
+		case "Threads":
+			if threads, err := strconv.Atoi(value); err == nil {
+				info.Threads = threads
+			}
+		case "Uid":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				if uid, err := strconv.Atoi(fields[0]); err == nil {
+					info.UID = uid
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseCmdline splits the NUL separated content of /proc/<pid>/cmdline.
+func parseCmdline(content []byte) []string {
+	content = bytes.TrimRight(content, "\x00")
+	if len(content) == 0 {
+		return nil
+	}
+
+	return strings.Split(string(content), "\x00")
+}
+
+// readBootTime returns the system boot time from the btime line of /proc/stat.
+func readBootTime() (time.Time, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("parse btime: %w", err)
+			}
+
+			return time.Unix(sec, 0), nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidProcStat
+}
+
+// ticksToDuration converts clock ticks to a duration.
+func ticksToDuration(ticks uint64) time.Duration {
+	return time.Duration(ticks) * time.Second / clockTicks
+}
+
+// formatElapsed formats a duration the way `ps -o etime` does:
+// [[DD-]HH:]MM:SS.
+func formatElapsed(elapsed time.Duration) string {
+	total := int64(max(elapsed, 0) / time.Second)
+
+	days := total / 86400
+	hours := total / 3600 % 24
+	minutes := total / 60 % 60
+	seconds := total % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d-%02d:%02d:%02d", days, hours, minutes, seconds)
+	case hours > 0:
+		return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	default:
+		return fmt.Sprintf("%02d:%02d", minutes, seconds)
+	}
+}
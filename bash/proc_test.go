@@ -0,0 +1,113 @@
+package bash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProcess(t *testing.T) {
+	t.Run("current process", func(t *testing.T) {
+		info, err := ReadProcess(os.Getpid())
+		require.NoError(t, err)
+
+		assert.Equal(t, os.Getpid(), info.PID)
+		assert.Equal(t, os.Getppid(), info.PPID)
+		assert.Equal(t, os.Getuid(), info.UID)
+		assert.Equal(t, os.Args, info.Cmdline)
+		assert.NotZero(t, info.RSS)
+		assert.Positive(t, info.Threads)
+		assert.False(t, info.StartTime.IsZero())
+		assert.Less(t, info.StartTime, time.Now())
+	})
+
+	t.Run("invalid pid", func(t *testing.T) {
+		_, err := ReadProcess(0)
+		assert.ErrorIs(t, err, ErrInvalidPID)
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		_, err := ReadProcess(1 << 30)
+		assert.ErrorIs(t, err, ErrNotRunning)
+	})
+}
+
+func TestProcesses(t *testing.T) {
+	list, err := Processes()
+	require.NoError(t, err)
+
+	var found bool
+
+	for _, info := range list {
+		if info.PID == os.Getpid() {
+			found = true
+		}
+	}
+
+	assert.True(t, found, "current process should be listed")
+}
+
+func TestReadProcessFakeTree(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "42")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "stat"), []byte("cpu  1 2 3 4\nbtime 1700000000\n"), 0o644))
+	write("stat", "42 (my (odd) name) S 1 42 42 0 -1 4194560 100 0 0 0 250 50 10 5 20 0 3 0 1000 0 0")
+	write("status", "Name:\tmy (odd) name\nState:\tS (sleeping)\nUid:\t1000\t1000\t1000\t1000\nThreads:\t3\n")
+	write("cmdline", "server\x00--port\x008080\x00")
+	write("statm", "1000 25 10 1 0 50 0\n")
+
+	defer func(path string) { procPath = path }(procPath)
+	procPath = root
+
+	info, err := ReadProcess(42)
+	require.NoError(t, err)
+
+	assert.Equal(t, "my (odd) name", info.Name)
+	assert.Equal(t, 1, info.PPID)
+	assert.Equal(t, 1000, info.UID)
+	assert.Equal(t, "S", info.State)
+	assert.Equal(t, 3, info.Threads)
+	assert.Equal(t, []string{"server", "--port", "8080"}, info.Cmdline)
+	assert.Equal(t, "server --port 8080", info.CommandLine())
+	assert.Equal(t, uint64(25*os.Getpagesize()), info.RSS)
+	assert.Equal(t, 2500*time.Millisecond, info.UserTime)
+	assert.Equal(t, 500*time.Millisecond, info.SystemTime)
+	assert.Equal(t, 3*time.Second, info.CPUTime())
+	assert.Equal(t, time.Unix(1700000010, 0), info.StartTime)
+
+	pid, err := PidofByProcess("server")
+	require.NoError(t, err)
+	assert.Equal(t, "42", pid)
+
+	pid, err = PidofByProcessAndParam("server", "--port 8080")
+	require.NoError(t, err)
+	assert.Equal(t, "42", pid)
+}
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		elapsed  time.Duration
+		expected string
+	}{
+		{0, "00:00"},
+		{59 * time.Second, "00:59"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "01:02:03"},
+		{49*time.Hour + 5*time.Second, "2-01:00:05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatElapsed(tt.elapsed))
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// DefaultWaitDelay is the time given to a cancelled command to release its
+// output pipes before they are forcibly closed.
+const DefaultWaitDelay = time.Second
+
+// Result describes a finished command.
+type Result struct {
+	// Stdout holds everything the command wrote to its standard output.
+	Stdout string
+
+	// Stderr holds everything the command wrote to its standard error.
+	Stderr string
+
+	// ExitCode is the exit status of the command or -1 if the command was
+	// terminated by a signal or could not be started.
+	ExitCode int
+
+	// Duration is the wall time spent between start and exit.
+	Duration time.Duration
+
+	// Signal is the signal that terminated the command, zero if the command
+	// exited on its own.
+	Signal syscall.Signal
+}
+
+// StderrPolicy defines when output on stderr makes a command fail.
+type StderrPolicy int
+
+const (
+	// StderrFail treats any output on stderr as a failure. This is the
+	// default policy and the behavior of Execute.
+	StderrFail StderrPolicy = iota
+
+	// StderrIgnore only treats a non-zero exit code as a failure.
+	StderrIgnore
+
+	// StderrMatch treats stderr as a failure only when it matches the pattern
+	// configured with WithStderrPattern.
+	StderrMatch
+)
+
+// ExecError describes a command that ran but failed according to the exit
+// code or the stderr policy of the Runner. It wraps ErrBashExecuteFailed and,
+// for non-zero exit codes, the underlying *exec.ExitError.
+//
+// Example:
+//
+//	var execErr *ExecError
+//	if errors.As(err, &execErr) && execErr.ExitCode == 1 {
+//	    // nothing found
+//	}
+type ExecError struct {
+	// Name and Args identify the failed command.
+	Name string
+	Args []string
+
+	// ExitCode is the exit status of the command or -1 if it was terminated
+	// by a signal.
+	ExitCode int
+
+	// Signal is the signal that terminated the command, if any.
+	Signal syscall.Signal
+
+	// Stderr holds the captured standard error output.
+	Stderr string
+
+	// Err is the error returned by exec.Cmd.Wait, nil if the command exited
+	// successfully but was rejected by the stderr policy.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ExecError) Error() string {
+	msg := ErrBashExecuteFailed.Error()
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+
+	return msg
+}
+
+// Unwrap makes ExecError match ErrBashExecuteFailed and the underlying
+// exec error with errors.Is and errors.As.
+func (e *ExecError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrBashExecuteFailed}
+	}
+
+	return []error{ErrBashExecuteFailed, e.Err}
+}
+
+// Runner executes system commands with context support.
+// Every command is started in its own process group, so cancelling the
+// context kills the command together with everything it has spawned. On
+// platforms without process groups, such as Windows, only the command itself
+// is killed.
+//
+// The zero value is not usable, create instances with NewRunner.
+// A Runner is safe for concurrent use.
+type Runner struct {
+	timeout       time.Duration
+	waitDelay     time.Duration
+	dir           string
+	env           []string
+	stderrPolicy  StderrPolicy
+	stderrPattern *regexp.Regexp
+	stripANSI     bool
+}
+
+// defaultRunner is used by Execute, ExecuteContext and the package helpers.
+var defaultRunner = NewRunner()
+
+// NewRunner creates a Runner configured with the given options.
+func NewRunner(options ...Option) *Runner {
+	runner := &Runner{
+		waitDelay: DefaultWaitDelay,
+	}
+
+	for _, option := range options {
+		option(runner)
+	}
+
+	return runner
+}
+
+// Run executes a command and waits for it to finish or for the context to be
+// done, whatever happens first.
+//
+// Parameters:
+//   - ctx:  Context controlling the command lifetime. When it is done the whole
+//     process group of the command receives SIGKILL.
+//   - name: The name/path of the command to execute (e.g. "ls", "/bin/bash")
+//   - args: Variadic arguments to pass to the command (e.g. "-l", "-a")
+//
+// Returns:
+//   - *Result: The captured output and exit information. It is returned even
+//     when error is not nil, unless the command could not be started.
+//   - error: Returns:
+//   - ctx.Err() wrapped if the context was done before the command exited
+//   - *ExecError if the command exited with non-zero status or its stderr
+//     is a failure according to the StderrPolicy of the Runner
+//   - Other errors if the command could not be started
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//
+//	res, err := NewRunner().Run(ctx, "ls", "-R", "/var")
+//	if errors.Is(err, context.DeadlineExceeded) {
+//	    // command took too long and was killed
+//	}
+func (r *Runner) Run(ctx context.Context, name string, args ...string) (*Result, error) {
+	return r.RunPipeline(ctx, Pipe(Cmd(name, args...)))
+}
+
+// command prepares an exec.Cmd bound to ctx that runs in its own process
+// group and takes the group down when ctx is done.
+func (r *Runner) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = r.dir
+	cmd.Env = r.env
+	cmd.WaitDelay = r.waitDelay
+	setProcessGroup(cmd, nil)
+
+	return cmd
+}
+
+// check converts the outcome of a finished command to an error.
+func (r *Runner) check(ctx context.Context, res *Result, err error, command *Command) error {
+	if err == nil && !r.stderrFails(res.Stderr) {
+		return nil
+	}
+
+	execErr := &ExecError{
+		Name:     command.Name,
+		Args:     command.Args,
+		ExitCode: res.ExitCode,
+		Signal:   res.Signal,
+		Stderr:   res.Stderr,
+		Err:      err,
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil && res.Signal != 0 {
+		return fmt.Errorf("%w: %w", ctxErr, execErr)
+	}
+
+	return execErr
+}
+
+// stderrFails reports whether stderr output is a failure under the policy.
+func (r *Runner) stderrFails(stderr string) bool {
+	if stderr == "" {
+		return false
+	}
+
+	switch r.stderrPolicy {
+	case StderrIgnore:
+		return false
+	case StderrMatch:
+		return r.stderrPattern != nil && r.stderrPattern.MatchString(stderr)
+	default:
+		return true
+	}
+}
+
+// ExecuteContext runs a system command with the default Runner and returns its
+// standard output. It behaves like Execute but the command is killed together
+// with its process group as soon as ctx is done.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//
+//	out, err := ExecuteContext(ctx, "pgrep", "-af", "java")
+func ExecuteContext(ctx context.Context, name string, args ...string) (string, error) {
+	res, err := defaultRunner.Run(ctx, name, args...)
+	if res == nil {
+		return "", err
+	}
+
+	return res.Stdout, err
+}
+
+// exitStatus extracts the exit code and terminating signal of a finished command.
+func exitStatus(cmd *exec.Cmd) (int, syscall.Signal) {
+	if cmd.ProcessState == nil {
+		return -1, 0
+	}
+
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return -1, status.Signal()
+	}
+
+	return cmd.ProcessState.ExitCode(), 0
+}
+
+// killGroup sends sig to the process group led by the command.
+func killGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	return signalGroup(cmd.Process.Pid, sig)
+}
@@ -0,0 +1,127 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerRun(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		res, err := NewRunner().Run(context.Background(), "echo", "hello")
+		require.NoError(t, err)
+
+		assert.Equal(t, "hello\n", res.Stdout)
+		assert.Empty(t, res.Stderr)
+		assert.Equal(t, 0, res.ExitCode)
+		assert.Zero(t, res.Signal)
+		assert.Positive(t, res.Duration)
+	})
+
+	t.Run("exit code", func(t *testing.T) {
+		res, err := NewRunner().Run(context.Background(), "bash", "-c", "exit 3")
+
+		var exitErr *exec.ExitError
+		require.True(t, errors.As(err, &exitErr))
+		assert.Equal(t, 3, res.ExitCode)
+	})
+
+	t.Run("stderr", func(t *testing.T) {
+		res, err := NewRunner().Run(context.Background(), "bash", "-c", "echo oops >&2")
+		assert.ErrorIs(t, err, ErrBashExecuteFailed)
+		assert.Equal(t, "oops\n", res.Stderr)
+	})
+
+	t.Run("invalid command", func(t *testing.T) {
+		_, err := NewRunner().Run(context.Background(), "")
+		assert.ErrorIs(t, err, ErrInvalidCommand)
+
+		_, err = NewRunner().Run(context.Background(), NonExistentProcessName)
+		assert.Error(t, err)
+	})
+
+	t.Run("working directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		res, err := NewRunner(WithDir(dir)).Run(context.Background(), "pwd")
+		require.NoError(t, err)
+		assert.Equal(t, dir+"\n", res.Stdout)
+	})
+}
+
+func TestRunnerCancel(t *testing.T) {
+	t.Run("context deadline kills process group", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		startedAt := time.Now()
+
+		// The background sleep inherits stdout and would keep Run waiting
+		// if only the direct child were killed.
+		res, err := NewRunner().Run(ctx, "bash", "-c", "sleep 10 & sleep 10")
+		require.Error(t, err)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(startedAt), 5*time.Second)
+		assert.Equal(t, -1, res.ExitCode)
+		assert.Equal(t, syscall.SIGKILL, res.Signal)
+	})
+
+	t.Run("runner timeout", func(t *testing.T) {
+		_, err := NewRunner(WithTimeout(50*time.Millisecond)).Run(context.Background(), "sleep", "10")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestExecuteContext(t *testing.T) {
+	out, err := ExecuteContext(context.Background(), "echo", "-n", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+}
+
+func TestRunnerStderrPolicy(t *testing.T) {
+	const script = "echo warning: disk almost full >&2"
+
+	t.Run("fail on stderr by default", func(t *testing.T) {
+		_, err := NewRunner().Run(context.Background(), "bash", "-c", script)
+		require.Error(t, err)
+
+		var execErr *ExecError
+		require.True(t, errors.As(err, &execErr))
+		assert.Equal(t, 0, execErr.ExitCode)
+		assert.Equal(t, "warning: disk almost full\n", execErr.Stderr)
+		assert.Equal(t, "bash execute failed: warning: disk almost full\n", err.Error())
+	})
+
+	t.Run("ignore stderr", func(t *testing.T) {
+		res, err := NewRunner(WithStderrPolicy(StderrIgnore)).Run(context.Background(), "bash", "-c", script)
+		require.NoError(t, err)
+		assert.Equal(t, "warning: disk almost full\n", res.Stderr)
+	})
+
+	t.Run("ignore stderr still fails on exit code", func(t *testing.T) {
+		_, err := NewRunner(WithStderrPolicy(StderrIgnore)).Run(context.Background(), "bash", "-c", script+"; exit 2")
+
+		var execErr *ExecError
+		require.True(t, errors.As(err, &execErr))
+		assert.Equal(t, 2, execErr.ExitCode)
+		assert.ErrorIs(t, err, ErrBashExecuteFailed)
+	})
+
+	t.Run("stderr pattern", func(t *testing.T) {
+		runner := NewRunner(WithStderrPattern(regexp.MustCompile(`(?i)error`)))
+
+		_, err := runner.Run(context.Background(), "bash", "-c", script)
+		assert.NoError(t, err)
+
+		_, err = runner.Run(context.Background(), "bash", "-c", "echo ERROR: broken >&2")
+		assert.ErrorIs(t, err, ErrBashExecuteFailed)
+	})
+}
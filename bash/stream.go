@@ -0,0 +1,323 @@
+package bash
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxLineLength is the longest line Stream can deliver. Commands writing
+	// longer lines without a newline are cancelled with bufio.ErrTooLong.
+	MaxLineLength = 1024 * 1024
+
+	// stderrTailSize is the amount of stderr kept by Stream for the stderr
+	// policy and ExecError.
+	stderrTailSize = 64 * 1024
+)
+
+// StreamKind identifies the output stream a line was read from.
+type StreamKind int
+
+const (
+	// StreamStdout marks lines read from the standard output.
+	StreamStdout StreamKind = iota + 1
+
+	// StreamStderr marks lines read from the standard error.
+	StreamStderr
+)
+
+// String returns the conventional name of the stream.
+func (k StreamKind) String() string {
+	switch k {
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// Line is a single line of command output without the trailing newline.
+type Line struct {
+	Stream StreamKind
+	Text   string
+}
+
+// LineHandler receives output lines of a streamed command. Calls are never
+// concurrent, so the handler does not need to be thread-safe. Returning an
+// error stops the command.
+type LineHandler func(line Line) error
+
+// Stream runs a command and calls handler for every line written to its
+// stdout or stderr as soon as the line arrives.
+//
+// Parameters:
+//   - ctx:     Context controlling the command lifetime, as in Run.
+//   - handler: Function called for every output line.
+//   - name:    The name/path of the command to execute.
+//   - args:    Variadic arguments to pass to the command.
+//
+// Returns:
+//   - *Result: Exit information. Stdout is not buffered and is always empty,
+//     Stderr holds up to the last 64 KiB of the standard error.
+//   - error: The error returned by handler, if any, otherwise the same errors
+//     as Run.
+//
+// Behavior:
+//   - The handler is called synchronously; while it is busy the command is
+//     blocked on a full pipe, which gives natural backpressure
+//   - ANSI escape sequences are removed from lines when the Runner is created
+//     with WithStripANSI
+//   - Lines longer than MaxLineLength stop the command with bufio.ErrTooLong
+//
+// Example:
+//
+//	_, err := NewRunner(WithStripANSI()).Stream(ctx, func(line Line) error {
+//	    log.Println(line.Stream, line.Text)
+//	    return nil
+//	}, "tail", "-f", "/var/log/server.log")
+func (r *Runner) Stream(ctx context.Context, handler LineHandler, name string, args ...string) (*Result, error) {
+	if name == "" || handler == nil {
+		return nil, ErrInvalidCommand
+	}
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	cmd := r.command(ctx, name, args...)
+	stdout, stderr, closeOutput := outputPipes(cmd)
+
+	startedAt := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		closeOutput()
+
+		return nil, err
+	}
+
+	emitter := &lineEmitter{handler: handler, cancel: cancel, strip: r.stripANSI}
+	tail := &tailBuffer{limit: stderrTailSize}
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		emitter.scan(stdout, StreamStdout, nil)
+	}()
+
+	go func() {
+		defer wg.Done()
+		emitter.scan(stderr, StreamStderr, tail)
+	}()
+
+	// Wait returns at most WaitDelay after the exit or cancellation, even if
+	// a descendant in another session keeps the pipes open.
+	err := cmd.Wait()
+
+	closeOutput()
+	wg.Wait()
+
+	res := &Result{
+		Stderr:   tail.String(),
+		Duration: time.Since(startedAt),
+	}
+
+	res.ExitCode, res.Signal = exitStatus(cmd)
+
+	if emitter.err != nil {
+		return res, emitter.err
+	}
+
+	return res, r.check(ctx, res, err, Cmd(name, args...))
+}
+
+// ToChannel returns a LineHandler sending lines to ch. The send blocks until
+// the receiver is ready or ctx is done, in which case the command is stopped.
+//
+// Example:
+//
+//	lines := make(chan Line)
+//	go func() {
+//	    defer close(lines)
+//	    _, _ = NewRunner().Stream(ctx, ToChannel(ctx, lines), "journalctl", "-f")
+//	}()
+//
+//	for line := range lines {
+//	    fmt.Println(line.Text)
+//	}
+func ToChannel(ctx context.Context, ch chan<- Line) LineHandler {
+	return func(line Line) error {
+		select {
+		case ch <- line:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// outputPipes connects the stdout and stderr of cmd to the returned readers.
+// The output is copied by cmd itself, so Wait drains it and honors
+// WaitDelay. closeOutput must be called once Wait has returned, it ends the
+// readers after the remaining output.
+func outputPipes(cmd *exec.Cmd) (io.Reader, io.Reader, func()) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	return stdoutReader, stderrReader, func() {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+	}
+}
+
+// lineEmitter serializes handler calls coming from stdout and stderr readers
+// and remembers the first handler error. With truncate set, lines longer than
+// MaxLineLength are cut instead of failing the scan.
+type lineEmitter struct {
+	mu       sync.Mutex
+	handler  LineHandler
+	cancel   context.CancelCauseFunc
+	strip    bool
+	truncate bool
+	err      error
+}
+
+// scan reads lines from reader until EOF and passes them to the handler.
+// Raw lines are also copied to tail when it is not nil.
+func (e *lineEmitter) scan(reader io.Reader, kind StreamKind, tail *tailBuffer) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLineLength)
+
+	if e.truncate {
+		scanner.Split(truncateLines())
+	}
+
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		if tail != nil {
+			tail.WriteLine(text)
+		}
+
+		if e.strip {
+			text = ansiRegexp.ReplaceAllString(text, "")
+		}
+
+		e.emit(Line{Stream: kind, Text: text})
+	}
+
+	if err := scanner.Err(); err != nil {
+		e.fail(err)
+
+		// Keep the pipe drained so the command is not blocked until it dies.
+		_, _ = io.Copy(io.Discard, reader)
+	}
+}
+
+// truncateLines is bufio.ScanLines returning the first MaxLineLength bytes
+// of longer lines once their end is read.
+func truncateLines() bufio.SplitFunc {
+	var long []byte
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		i := bytes.IndexByte(data, '\n')
+
+		if long != nil {
+			if i < 0 && !atEOF {
+				return len(data), nil, nil
+			}
+
+			advance := len(data)
+			if i >= 0 {
+				advance = i + 1
+			}
+
+			token := long
+			long = nil
+
+			return advance, token, nil
+		}
+
+		switch {
+		case i >= 0:
+			return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+		case len(data) >= MaxLineLength:
+			long = bytes.Clone(data[:MaxLineLength])
+
+			return len(data), nil, nil
+		case atEOF && len(data) > 0:
+			return len(data), bytes.TrimSuffix(data, []byte{'\r'}), nil
+		default:
+			return 0, nil, nil
+		}
+	}
+}
+
+func (e *lineEmitter) emit(line Line) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err != nil {
+		return
+	}
+
+	if err := e.handler(line); err != nil {
+		e.err = err
+		e.cancel(err)
+	}
+}
+
+func (e *lineEmitter) fail(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err == nil {
+		e.err = err
+		e.cancel(err)
+	}
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+// WriteLine appends text and a newline dropping the oldest bytes over limit.
+func (b *tailBuffer) WriteLine(text string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, text...)
+	b.buf = append(b.buf, '\n')
+
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.buf)
+}
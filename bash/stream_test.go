@@ -0,0 +1,118 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerStream(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		var lines []Line
+
+		res, err := NewRunner(WithStderrPolicy(StderrIgnore)).Stream(context.Background(), func(line Line) error {
+			lines = append(lines, line)
+
+			return nil
+		}, "bash", "-c", "echo one; echo two; echo oops >&2")
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, res.ExitCode)
+		assert.Empty(t, res.Stdout)
+		assert.Equal(t, "oops\n", res.Stderr)
+		assert.ElementsMatch(t, []Line{
+			{Stream: StreamStdout, Text: "one"},
+			{Stream: StreamStdout, Text: "two"},
+			{Stream: StreamStderr, Text: "oops"},
+		}, lines)
+	})
+
+	t.Run("strip ansi", func(t *testing.T) {
+		var lines []string
+
+		_, err := NewRunner(WithStripANSI()).Stream(context.Background(), func(line Line) error {
+			lines = append(lines, line.Text)
+
+			return nil
+		}, "printf", "\033[32mgreen\033[0m\n")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"green"}, lines)
+	})
+
+	t.Run("handler error stops command", func(t *testing.T) {
+		errStop := errors.New("stop")
+
+		var count int
+
+		startedAt := time.Now()
+
+		_, err := NewRunner().Stream(context.Background(), func(_ Line) error {
+			count++
+			if count == 3 {
+				return errStop
+			}
+
+			return nil
+		}, "yes")
+
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 3, count)
+		assert.Less(t, time.Since(startedAt), 5*time.Second)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := NewRunner().Stream(ctx, func(_ Line) error { return nil }, "sleep", "10")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("cancel with orphaned pipes", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		startedAt := time.Now()
+
+		_, err := NewRunner(WithWaitDelay(100*time.Millisecond)).Stream(ctx, func(_ Line) error { return nil },
+			"bash", "-c", "setsid sleep 3 & echo started; sleep 10")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(startedAt), 2*time.Second)
+	})
+
+	t.Run("invalid command", func(t *testing.T) {
+		_, err := NewRunner().Stream(context.Background(), nil, "echo")
+		assert.ErrorIs(t, err, ErrInvalidCommand)
+	})
+}
+
+func TestToChannel(t *testing.T) {
+	ctx := context.Background()
+	lines := make(chan Line)
+
+	go func() {
+		defer close(lines)
+
+		_, _ = NewRunner().Stream(ctx, ToChannel(ctx, lines), "printf", "a\\nb\\n")
+	}()
+
+	var texts []string
+	for line := range lines {
+		texts = append(texts, line.Text)
+	}
+
+	assert.Equal(t, []string{"a", "b"}, texts)
+}
+
+func TestTailBuffer(t *testing.T) {
+	tail := &tailBuffer{limit: 8}
+	tail.WriteLine("first")
+	tail.WriteLine("second")
+
+	assert.Equal(t, "\nsecond\n", tail.String())
+}
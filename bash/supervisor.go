@@ -0,0 +1,367 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMinBackoff is the delay before the first restart of a failed process.
+	DefaultMinBackoff = time.Second
+
+	// DefaultMaxBackoff is the upper limit of the restart delay.
+	DefaultMaxBackoff = time.Minute
+
+	// DefaultStopTimeout is the time given to a process to exit after SIGTERM
+	// before it is killed with SIGKILL.
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// Logger describes the minimal logging interface required by the Supervisor.
+// Implementations should provide Debug and Error logging capabilities.
+type Logger interface {
+	Debug(args ...interface{})
+	Error(args ...interface{})
+}
+
+// State describes the lifecycle phase of a supervised process.
+type State int
+
+const (
+	// StateStopped means the supervisor is not started or has been stopped.
+	StateStopped State = iota
+
+	// StateRunning means the process is alive.
+	StateRunning
+
+	// StateBackoff means the process has exited and waits to be restarted.
+	StateBackoff
+
+	// StateStopping means the process is being terminated by Stop.
+	StateStopping
+)
+
+// String returns a human readable name of the state.
+func (s State) String() string {
+	switch s {
+	case StateStopped:
+		return "stopped"
+	case StateRunning:
+		return "running"
+	case StateBackoff:
+		return "backoff"
+	case StateStopping:
+		return "stopping"
+	default:
+		return "unknown"
+	}
+}
+
+// Supervisor starts a command and keeps it alive. When the process exits it
+// is restarted after an exponentially growing delay, which is reset once the
+// process has been running for longer than the maximum delay.
+//
+// Stop terminates the whole process group with SIGTERM and falls back to
+// SIGKILL after the stop timeout, on platforms without signals, such as
+// Windows, the process is killed at once. A process whose descendants keep
+// its output open is considered exited after the wait delay of the Runner.
+// Output lines longer than MaxLineLength are truncated. Clean exits are
+// logged with Debug, failures with Error.
+type Supervisor struct {
+	name    string
+	command *Command
+	logger  Logger
+	runner  *Runner
+	output  LineHandler
+
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	stopTimeout time.Duration
+
+	mu        sync.Mutex
+	state     State
+	pid       int
+	startedAt time.Time
+	restarts  int
+	quit      chan struct{}
+	done      chan struct{}
+}
+
+// NewSupervisor creates a configured but unstarted Supervisor.
+//
+// Parameters:
+//
+//	name    - identifier for logging.
+//	command - command to run and keep alive.
+//	l       - logger implementation.
+func NewSupervisor(name string, command *Command, l Logger, options ...SupervisorOption) *Supervisor {
+	supervisor := &Supervisor{
+		name:        name,
+		command:     command,
+		logger:      l,
+		runner:      defaultRunner,
+		minBackoff:  DefaultMinBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		stopTimeout: DefaultStopTimeout,
+	}
+
+	for _, option := range options {
+		option(supervisor)
+	}
+
+	return supervisor
+}
+
+// Start begins supervising the command in a new goroutine.
+// Safe to call multiple times (will log and ignore subsequent calls).
+func (s *Supervisor) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		s.logger.Debug(s.name + ": already been started")
+
+		return
+	}
+
+	s.quit = make(chan struct{})
+	s.done = make(chan struct{})
+	s.restarts = 0
+
+	go s.run(s.quit, s.done)
+}
+
+// Stop terminates the process and stops restarting it. It blocks until the
+// process has exited. Safe to call multiple times.
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+
+	if s.done == nil {
+		s.mu.Unlock()
+		s.logger.Debug(s.name + ": is not running")
+
+		return
+	}
+
+	// done is cleared only once the old run has finished, so a concurrent
+	// Start cannot run a second process alongside it.
+	quit, done := s.quit, s.done
+	s.quit = nil
+
+	s.mu.Unlock()
+
+	if quit != nil {
+		close(quit)
+	}
+
+	<-done
+
+	s.mu.Lock()
+	if s.done == done {
+		s.done = nil
+	}
+	s.mu.Unlock()
+}
+
+// State returns the current lifecycle phase.
+func (s *Supervisor) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// PID returns the process id of the running process or 0.
+func (s *Supervisor) PID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pid
+}
+
+// Uptime returns for how long the current process has been running.
+// Returns 0 if the process is not running.
+func (s *Supervisor) Uptime() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != StateRunning {
+		return 0
+	}
+
+	return time.Since(s.startedAt)
+}
+
+// Restarts returns how many times the process has been restarted since Start.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.restarts
+}
+
+// run is the supervision loop running in a goroutine until quit is closed.
+func (s *Supervisor) run(quit, done chan struct{}) {
+	defer func() {
+		s.setState(StateStopped, 0)
+		close(done)
+	}()
+
+	backoff := s.minBackoff
+
+	for {
+		startedAt := time.Now()
+
+		exited, err := s.spawn()
+		if err != nil {
+			s.logger.Error(s.name+": start:", err)
+		} else {
+			select {
+			case err := <-exited:
+				if err != nil {
+					s.logger.Error(s.name+": exited:", err)
+				} else {
+					s.logger.Debug(s.name + ": exited: exit status 0")
+				}
+			case <-quit:
+				s.terminate(exited)
+
+				return
+			}
+		}
+
+		if time.Since(startedAt) > s.maxBackoff {
+			backoff = s.minBackoff
+		}
+
+		s.setState(StateBackoff, 0)
+		s.logger.Debug(fmt.Sprintf("%s: restarting in %s", s.name, backoff))
+
+		select {
+		case <-time.After(backoff):
+		case <-quit:
+			return
+		}
+
+		backoff = min(2*backoff, s.maxBackoff)
+
+		s.mu.Lock()
+		s.restarts++
+		s.mu.Unlock()
+	}
+}
+
+// spawn starts the process and returns a channel receiving its exit error.
+func (s *Supervisor) spawn() (<-chan error, error) {
+	// The process is stopped by terminate rather than by a context.
+	cmd := s.runner.command(context.Background(), s.command.Name, s.command.Args...)
+
+	var outputs []io.Reader
+
+	closeOutput := func() {}
+
+	if s.output != nil {
+		var stdout, stderr io.Reader
+
+		stdout, stderr, closeOutput = outputPipes(cmd)
+		outputs = []io.Reader{stdout, stderr}
+	}
+
+	if err := cmd.Start(); err != nil {
+		closeOutput()
+
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.state = StateRunning
+	s.pid = cmd.Process.Pid
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
+	s.logger.Debug(fmt.Sprintf("%s: started with pid %d", s.name, cmd.Process.Pid))
+
+	emitter := &lineEmitter{handler: s.handleLine, cancel: func(error) {}, strip: s.runner.stripANSI, truncate: true}
+
+	var scans sync.WaitGroup
+
+	kinds := []StreamKind{StreamStdout, StreamStderr}
+
+	for i, output := range outputs {
+		scans.Add(1)
+
+		go func(output io.Reader, kind StreamKind) {
+			defer scans.Done()
+			emitter.scan(output, kind, nil)
+		}(output, kinds[i])
+	}
+
+	exited := make(chan error, 1)
+
+	go func() {
+		// Wait returns at most WaitDelay after the exit, even if a daemonized
+		// descendant keeps the output open.
+		err := cmd.Wait()
+
+		closeOutput()
+		scans.Wait()
+
+		exited <- err
+	}()
+
+	return exited, nil
+}
+
+// terminate stops the process group gracefully and waits for the exit.
+func (s *Supervisor) terminate(exited <-chan error) {
+	s.mu.Lock()
+	pid := s.pid
+	s.state = StateStopping
+	s.mu.Unlock()
+
+	s.logger.Debug(s.name + ": stopping...")
+
+	err := signalGroup(pid, syscall.SIGTERM)
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		s.logger.Error(s.name+": sigterm:", err)
+	}
+
+	// Without SIGTERM support the process is killed at once.
+	if !errors.Is(err, errors.ErrUnsupported) {
+		select {
+		case <-exited:
+			return
+		case <-time.After(s.stopTimeout):
+			s.logger.Error(s.name + ": forced shutdown due to timeout")
+		}
+	}
+
+	if err := signalGroup(pid, syscall.SIGKILL); err != nil {
+		s.logger.Error(s.name+": sigkill:", err)
+	}
+
+	<-exited
+}
+
+// handleLine passes a line to the output handler and logs handler errors
+// instead of stopping the process.
+func (s *Supervisor) handleLine(line Line) error {
+	if err := s.output(line); err != nil {
+		s.logger.Error(s.name+": output handler:", err)
+	}
+
+	return nil
+}
+
+func (s *Supervisor) setState(state State, pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+	s.pid = pid
+}
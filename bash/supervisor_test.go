@@ -0,0 +1,193 @@
+package bash
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockLogger implements Logger interface for testing
+type MockLogger struct {
+	debugs []string
+	errors []string
+	mu     sync.Mutex
+}
+
+func (m *MockLogger) Debug(args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.debugs = append(m.debugs, fmt.Sprint(args...))
+}
+
+func (m *MockLogger) Error(args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errors = append(m.errors, fmt.Sprint(args...))
+}
+
+func (m *MockLogger) Errors() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.errors...)
+}
+
+func (m *MockLogger) Debugs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.debugs...)
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	logger := &MockLogger{}
+
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+
+	supervisor := NewSupervisor("test", Cmd("bash", "-c", "echo started; exit 3"), logger,
+		WithBackoff(10*time.Millisecond, 40*time.Millisecond),
+		WithOutputHandler(func(line Line) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			lines = append(lines, line.Text)
+
+			return nil
+		}),
+	)
+
+	assert.Equal(t, StateStopped, supervisor.State())
+
+	supervisor.Start()
+	supervisor.Start()
+
+	require.Eventually(t, func() bool { return supervisor.Restarts() >= 3 }, 5*time.Second, 10*time.Millisecond)
+
+	supervisor.Stop()
+	supervisor.Stop()
+
+	assert.Equal(t, StateStopped, supervisor.State())
+	assert.Zero(t, supervisor.PID())
+
+	mu.Lock()
+	assert.GreaterOrEqual(t, len(lines), 3)
+	assert.Equal(t, "started", lines[0])
+	mu.Unlock()
+
+	assert.Contains(t, logger.Errors(), "test: exited:exit status 3")
+}
+
+func TestSupervisorCleanExit(t *testing.T) {
+	logger := &MockLogger{}
+
+	supervisor := NewSupervisor("test", Cmd("true"), logger, WithBackoff(10*time.Millisecond, 40*time.Millisecond))
+	supervisor.Start()
+
+	require.Eventually(t, func() bool { return supervisor.Restarts() >= 1 }, 5*time.Second, 10*time.Millisecond)
+
+	supervisor.Stop()
+
+	assert.Contains(t, logger.Debugs(), "test: exited: exit status 0")
+	assert.Empty(t, logger.Errors())
+}
+
+func TestSupervisorStop(t *testing.T) {
+	t.Run("graceful", func(t *testing.T) {
+		supervisor := NewSupervisor("test", Cmd("sleep", "10"), &MockLogger{})
+		supervisor.Start()
+
+		require.Eventually(t, func() bool { return supervisor.State() == StateRunning }, 5*time.Second, 10*time.Millisecond)
+
+		pid := supervisor.PID()
+		assert.Positive(t, pid)
+		assert.GreaterOrEqual(t, supervisor.Uptime(), time.Duration(0))
+
+		startedAt := time.Now()
+		supervisor.Stop()
+
+		assert.Less(t, time.Since(startedAt), time.Second)
+		assert.Zero(t, supervisor.Uptime())
+
+		_, err := ReadProcess(pid)
+		assert.ErrorIs(t, err, ErrNotRunning)
+	})
+
+	t.Run("kill after timeout", func(t *testing.T) {
+		logger := &MockLogger{}
+
+		supervisor := NewSupervisor("test", Cmd("bash", "-c", "trap '' TERM; echo ready; sleep 10"), logger,
+			WithStopTimeout(100*time.Millisecond),
+			WithOutputHandler(func(_ Line) error { return nil }),
+		)
+		supervisor.Start()
+
+		require.Eventually(t, func() bool { return supervisor.State() == StateRunning }, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+
+		startedAt := time.Now()
+		supervisor.Stop()
+
+		assert.Less(t, time.Since(startedAt), 5*time.Second)
+		assert.Contains(t, logger.Errors(), "test: forced shutdown due to timeout")
+	})
+
+	t.Run("daemonized descendant", func(t *testing.T) {
+		supervisor := NewSupervisor("test", Cmd("bash", "-c", "setsid sleep 3 & echo ready; sleep 10"), &MockLogger{},
+			WithRunner(NewRunner(WithWaitDelay(100*time.Millisecond))),
+			WithOutputHandler(func(_ Line) error { return nil }),
+		)
+		supervisor.Start()
+
+		require.Eventually(t, func() bool { return supervisor.State() == StateRunning }, 5*time.Second, 10*time.Millisecond)
+
+		startedAt := time.Now()
+		supervisor.Stop()
+
+		assert.Less(t, time.Since(startedAt), 2*time.Second)
+		assert.Equal(t, StateStopped, supervisor.State())
+	})
+}
+
+func TestSupervisorLongLine(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+
+	script := fmt.Sprintf("head -c %d /dev/zero | tr '\\0' a; echo; echo after; sleep 10", MaxLineLength+100)
+
+	supervisor := NewSupervisor("test", Cmd("bash", "-c", script), &MockLogger{},
+		WithOutputHandler(func(line Line) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			lines = append(lines, line.Text)
+
+			return nil
+		}),
+	)
+	supervisor.Start()
+	defer supervisor.Stop()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(lines) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, lines[0], MaxLineLength)
+	assert.Equal(t, "after", lines[1])
+}
@@ -0,0 +1,157 @@
+package bash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// waitExitInterval is how often WaitExit checks whether a process is gone.
+const waitExitInterval = 50 * time.Millisecond
+
+// Children returns the direct children of the process ordered by PID.
+func Children(pid int) ([]*ProcessInfo, error) {
+	list, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]*ProcessInfo, 0)
+
+	for _, info := range list {
+		if info.PPID == pid {
+			children = append(children, info)
+		}
+	}
+
+	return children, nil
+}
+
+// Descendants returns all children, grandchildren and so on of the process.
+// Parents always precede their children in the returned slice.
+func Descendants(pid int) ([]*ProcessInfo, error) {
+	list, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	return descendants(list, pid), nil
+}
+
+// Signal sends sig to the process.
+// Returns ErrNotRunning if the process does not exist. On platforms without
+// signals, such as Windows, only SIGKILL is supported and other signals
+// return an error wrapping errors.ErrUnsupported.
+//
+// Example:
+//
+//	err := Signal(pid, syscall.SIGHUP) // ask the server to reload its config
+func Signal(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidPID, pid)
+	}
+
+	if err := signalProcess(pid, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("%w: %d", ErrNotRunning, pid)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// SignalTree sends sig to the process and all its descendants.
+//
+// Behavior:
+//   - The tree is collected before any signal is sent, so children
+//     reparented to init by the signal are still reached
+//   - The root is signalled first, then its descendants parents first. Only
+//     SIGKILL and SIGSTOP stop the root at once; with a signal it handles,
+//     such as SIGTERM, children it spawns in the meantime are not reached
+//   - Descendants exiting in the meantime are ignored
+//   - Returns ErrNotRunning if the root process does not exist
+func SignalTree(pid int, sig syscall.Signal) error {
+	tree, err := Descendants(pid)
+	if err != nil {
+		return err
+	}
+
+	if err := Signal(pid, sig); err != nil {
+		return err
+	}
+
+	for _, info := range tree {
+		if err := Signal(info.PID, sig); err != nil && !errors.Is(err, ErrNotRunning) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// KillTree sends SIGKILL to the process and all its descendants.
+func KillTree(pid int) error {
+	return SignalTree(pid, syscall.SIGKILL)
+}
+
+// WaitExit blocks until the process exits or ctx is done. Zombies are
+// considered exited. Use context.WithTimeout to limit the wait.
+//
+// Example:
+//
+//	_ = Signal(pid, syscall.SIGTERM)
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//
+//	if err := WaitExit(ctx, pid); errors.Is(err, context.DeadlineExceeded) {
+//	    _ = KillTree(pid)
+//	}
+func WaitExit(ctx context.Context, pid int) error {
+	ticker := time.NewTicker(waitExitInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := ReadProcess(pid)
+		if errors.Is(err, ErrNotRunning) || (err == nil && info.State == "Z") {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// descendants walks the parent links of list starting at pid.
+func descendants(list []*ProcessInfo, pid int) []*ProcessInfo {
+	children := make(map[int][]*ProcessInfo)
+	for _, info := range list {
+		children[info.PPID] = append(children[info.PPID], info)
+	}
+
+	result := make([]*ProcessInfo, 0)
+	queue := []int{pid}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, child := range children[current] {
+			result = append(result, child)
+			queue = append(queue, child.PID)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,173 @@
+package bash
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTree starts a shell with two sleeping children and returns the shell
+// command and the PIDs of the children once both are running.
+func startTree(t *testing.T) (*exec.Cmd, []int) {
+	t.Helper()
+
+	cmd := exec.Command("bash", "-c", "sleep 30 & sleep 30 & wait")
+	require.NoError(t, cmd.Start())
+
+	t.Cleanup(func() {
+		_ = KillTree(cmd.Process.Pid)
+		_ = cmd.Wait()
+	})
+
+	var children []*ProcessInfo
+
+	require.Eventually(t, func() bool {
+		var err error
+
+		children, err = Children(cmd.Process.Pid)
+
+		return err == nil && len(children) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return cmd, []int{children[0].PID, children[1].PID}
+}
+
+// startSleep starts sleep with the given duration argument and returns it
+// once it is running.
+func startSleep(t *testing.T, arg string) *exec.Cmd {
+	t.Helper()
+
+	cmd := exec.Command("sleep", arg)
+	require.NoError(t, cmd.Start())
+
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	require.Eventually(t, func() bool {
+		info, err := ReadProcess(cmd.Process.Pid)
+
+		return err == nil && info.Name == "sleep"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return cmd
+}
+
+func TestChildren(t *testing.T) {
+	cmd, pids := startTree(t)
+
+	children, err := Children(cmd.Process.Pid)
+	require.NoError(t, err)
+
+	for _, child := range children {
+		assert.Equal(t, cmd.Process.Pid, child.PPID)
+		assert.Equal(t, []string{"sleep", "30"}, child.Cmdline)
+	}
+
+	descendants, err := Descendants(os.Getpid())
+	require.NoError(t, err)
+
+	var found []int
+	for _, info := range descendants {
+		found = append(found, info.PID)
+	}
+
+	assert.Subset(t, found, append(pids, cmd.Process.Pid))
+}
+
+func TestKillTree(t *testing.T) {
+	cmd, pids := startTree(t)
+
+	require.NoError(t, KillTree(cmd.Process.Pid))
+	require.Error(t, cmd.Wait())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, pid := range pids {
+		assert.NoError(t, WaitExit(ctx, pid))
+	}
+
+	err := Signal(cmd.Process.Pid, syscall.SIGTERM)
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestWaitExit(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := WaitExit(ctx, os.Getpid())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		assert.NoError(t, WaitExit(context.Background(), 1<<30))
+	})
+}
+
+func TestSignal(t *testing.T) {
+	assert.ErrorIs(t, Signal(0, syscall.SIGTERM), ErrInvalidPID)
+	assert.ErrorIs(t, Signal(1<<30, syscall.SIGTERM), ErrNotRunning)
+	assert.NoError(t, Signal(os.Getpid(), 0))
+}
+
+func TestFindProcesses(t *testing.T) {
+	t.Run("by name", func(t *testing.T) {
+		found, err := FindProcesses(filepath.Base(os.Args[0]))
+		require.NoError(t, err)
+		require.NotEmpty(t, found)
+
+		var pids []int
+		for _, info := range found {
+			pids = append(pids, info.PID)
+		}
+
+		assert.Contains(t, pids, os.Getpid())
+
+		found, err = FindProcesses(NonExistentProcessName)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("by param", func(t *testing.T) {
+		cmd := startSleep(t, "32.5")
+
+		found, err := FindProcessesByParam("sleep", "32.5")
+		require.NoError(t, err)
+
+		pids := make(map[int][]string, len(found))
+		for _, info := range found {
+			pids[info.PID] = info.Cmdline
+		}
+
+		assert.Equal(t, []string{"sleep", "32.5"}, pids[cmd.Process.Pid])
+
+		// The PID is not a part of the matched command line.
+		found, err = FindProcessesByParam("sleep", strconv.Itoa(cmd.Process.Pid))
+		require.NoError(t, err)
+
+		for _, info := range found {
+			assert.NotEqual(t, cmd.Process.Pid, info.PID)
+		}
+
+		found, err = FindProcessesByParam(filepath.Base(os.Args[0]), os.Args[0])
+		require.NoError(t, err)
+
+		for _, info := range found {
+			assert.NotEqual(t, os.Getpid(), info.PID, "current process should be skipped")
+		}
+
+		_, err = FindProcessesByParam("", "32.5")
+		assert.ErrorIs(t, err, ErrInvalidCommand)
+	})
+}
@@ -0,0 +1,714 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownFormat indicates that the archive format cannot be determined
+// from the file name.
+var ErrUnknownFormat = errors.New("unknown archive format")
+
+// ArchiveFormat is the container format of an archive.
+type ArchiveFormat int
+
+const (
+	// FormatTar is an uncompressed tar archive.
+	FormatTar ArchiveFormat = iota
+
+	// FormatTarGz is a gzip compressed tar archive.
+	FormatTarGz
+
+	// FormatZip is a zip archive with deflate compression.
+	FormatZip
+)
+
+// String returns the usual file extension of the format without the dot.
+func (f ArchiveFormat) String() string {
+	switch f {
+	case FormatTar:
+		return "tar"
+	case FormatTarGz:
+		return "tar.gz"
+	case FormatZip:
+		return "zip"
+	default:
+		return fmt.Sprintf("ArchiveFormat(%d)", int(f))
+	}
+}
+
+// ArchiveFormatFromName detects the format from the extension of name:
+// ".tar", ".tar.gz", ".tgz" or ".zip".
+func ArchiveFormatFromName(name string) (ArchiveFormat, error) {
+	lower := strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTarGz, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return FormatTar, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+}
+
+// CreateArchive writes an archive of the tree under root to w. Entry names
+// are slash separated paths relative to root; the root itself is not stored.
+// Permissions, modification times and symbolic links are preserved, other
+// special files are skipped.
+//
+// Supported options: WithInclude, WithExclude, WithRegexp, WithMinSize,
+// WithMinAge, WithMaxAge, WithMaxDepth, WithSkipHidden and WithConcurrency.
+// Directories not matching the filters are still descended into unless
+// excluded.
+//
+// Example usage:
+//
+//	resp.Header().Set("Content-Type", "application/gzip")
+//	err := CreateArchive(ctx, resp, "/srv/game/saves", FormatTarGz, WithExclude("*.tmp"))
+func CreateArchive(ctx context.Context, w io.Writer, root string, format ArchiveFormat, opts ...WalkOption) error {
+	o := newWalkOptions(opts...)
+
+	var (
+		mu      sync.Mutex
+		entries = make([]Entry, 0)
+	)
+
+	err := walkTree(ctx, root, o, true, func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Sorted entries make archives reproducible and put parents first.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	switch format {
+	case FormatTar:
+		return writeTar(ctx, w, root, entries)
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+
+		if err := writeTar(ctx, gz, root, entries); err != nil {
+			return err
+		}
+
+		return gz.Close()
+	case FormatZip:
+		return writeZip(ctx, w, root, entries)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// CreateArchiveFile is like CreateArchive but writes to the file name, with
+// the format detected by ArchiveFormatFromName. The file is replaced
+// atomically, see WriteFileAtomic. A name inside root, which would archive
+// the archive being written, is refused with ErrUnsafePath.
+func CreateArchiveFile(ctx context.Context, name, root string, opts ...WalkOption) error {
+	format, err := ArchiveFormatFromName(name)
+	if err != nil {
+		return err
+	}
+
+	rootPath, err := resolvePath(root)
+	if err != nil {
+		return err
+	}
+
+	namePath, err := resolvePath(name)
+	if err != nil {
+		return err
+	}
+
+	if pathWithin(namePath, rootPath) {
+		return &fs.PathError{Op: "archive", Path: name, Err: ErrUnsafePath}
+	}
+
+	return writeAtomic(name, DefaultFilePerm, func(f *os.File) error {
+		return CreateArchive(ctx, f, root, format, opts...)
+	})
+}
+
+// ExtractArchive extracts the archive read from r into dst, creating dst if
+// needed.
+//
+// Behavior details:
+//   - Entries with absolute paths, ".." components or symbolic links
+//     pointing outside dst are refused with ErrUnsafePath before anything
+//     is written for them
+//   - Permission bits (without setuid, setgid and sticky) and modification
+//     times are restored, ownership is not
+//   - Existing files are replaced, existing directories are merged
+//   - Zip archives need random access, they are buffered to a temporary
+//     file unless r is an *os.File
+//   - Symbolic links, already in dst or created by earlier entries, are
+//     followed only while they stay inside dst, an entry reaching out of
+//     dst through them is refused with ErrUnsafePath as well
+//   - Link targets are stored cleaned, "a/../b" becomes "b"
+//
+// Supported options: WithExtractInclude and WithExtractExclude filtering the
+// entries by name. Entries inside excluded directories are skipped too.
+func ExtractArchive(ctx context.Context, r io.Reader, dst string, format ArchiveFormat, opts ...ExtractOption) error {
+	o := newExtractOptions(opts...)
+
+	if err := MkdirAll(dst); err != nil {
+		return err
+	}
+
+	root, err := resolvePath(dst)
+	if err != nil {
+		return err
+	}
+
+	x := &extractor{ctx: ctx, dst: root, opts: o}
+
+	switch format {
+	case FormatTar:
+		err = x.readTar(r)
+	case FormatTarGz:
+		var gz *gzip.Reader
+
+		gz, err = gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+
+		err = x.readTar(gz)
+	case FormatZip:
+		err = x.readZip(r)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return x.finishDirs()
+}
+
+// ExtractArchiveFile is like ExtractArchive but reads the file name, with
+// the format detected by ArchiveFormatFromName.
+func ExtractArchiveFile(ctx context.Context, name, dst string, opts ...ExtractOption) error {
+	format, err := ArchiveFormatFromName(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return ExtractArchive(ctx, f, dst, format, opts...)
+}
+
+// archiveName returns the slash separated name of the entry relative to root.
+func archiveName(root string, entry Entry) (string, error) {
+	rel, err := filepath.Rel(root, entry.Path)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.ToSlash(rel)
+	if entry.IsDir() {
+		name += "/"
+	}
+
+	return name, nil
+}
+
+func writeTar(ctx context.Context, w io.Writer, root string, entries []Entry) error {
+	tw := tar.NewWriter(w)
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := addTarEntry(ctx, tw, root, entry); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func addTarEntry(ctx context.Context, tw *tar.Writer, root string, entry Entry) error {
+	info, err := os.Lstat(entry.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	var link string
+
+	switch mode := info.Mode(); {
+	case mode&fs.ModeSymlink != 0:
+		if link, err = os.Readlink(entry.Path); err != nil {
+			return err
+		}
+	case !mode.IsRegular() && !mode.IsDir():
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+
+	if header.Name, err = archiveName(root, entry); err != nil {
+		return err
+	}
+
+	// PAX keeps sub-second times and long names.
+	header.Format = tar.FormatPAX
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	return copyFileTo(ctx, tw, entry.Path)
+}
+
+func writeZip(ctx context.Context, w io.Writer, root string, entries []Entry) error {
+	zw := zip.NewWriter(w)
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := addZipEntry(ctx, zw, root, entry); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func addZipEntry(ctx context.Context, zw *zip.Writer, root string, entry Entry) error {
+	info, err := os.Lstat(entry.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	mode := info.Mode()
+	if !mode.IsRegular() && !mode.IsDir() && mode&fs.ModeSymlink == 0 {
+		return nil
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	if header.Name, err = archiveName(root, entry); err != nil {
+		return err
+	}
+
+	if mode.IsRegular() {
+		header.Method = zip.Deflate
+	}
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case mode.IsRegular():
+		return copyFileTo(ctx, w, entry.Path)
+	case mode&fs.ModeSymlink != 0:
+		// Zip stores the link target as the content.
+		link, err := os.Readlink(entry.Path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, link)
+
+		return err
+	default:
+		return nil
+	}
+}
+
+// copyFileTo streams the file name to w.
+func copyFileTo(ctx context.Context, w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, &contextReader{ctx: ctx, r: f})
+
+	return err
+}
+
+// contextReader fails reads once ctx is done.
+type contextReader struct {
+	ctx context.Context //nolint:containedctx // scoped to a single copy
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
+
+// extractedDir is a directory whose metadata is applied after extraction,
+// when no more files are written into it.
+type extractedDir struct {
+	path    string
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// extractor holds the state of an ExtractArchive call.
+type extractor struct {
+	ctx  context.Context //nolint:containedctx // scoped to a single extraction
+	dst  string          // with symbolic links resolved
+	opts *extractOptions
+	dirs []extractedDir
+}
+
+func (x *extractor) readTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+
+	for {
+		if err := x.ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rel, ok, err := x.target(header.Name)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		mode := header.FileInfo().Mode()
+
+		// Devices and named pipes are skipped.
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(rel, mode, header.ModTime)
+		case tar.TypeReg, tar.TypeGNUSparse:
+			err = x.file(rel, tr, mode, header.ModTime)
+		case tar.TypeSymlink:
+			err = x.symlink(header.Name, rel, header.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(header.Name, rel, header.Linkname)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) readZip(r io.Reader) error {
+	f, ok := r.(*os.File)
+	if !ok {
+		tmp, err := os.CreateTemp("", "archive-*.zip")
+		if err != nil {
+			return err
+		}
+
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		if _, err := io.Copy(tmp, &contextReader{ctx: x.ctx, r: r}); err != nil {
+			return err
+		}
+
+		f = tmp
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
+		if err := x.ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, ok, err := x.target(file.Name)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := x.zipEntry(file, rel); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *extractor) zipEntry(file *zip.File, rel string) error {
+	mode := file.Mode()
+
+	if mode.IsDir() {
+		return x.dir(rel, mode, file.Modified)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&fs.ModeSymlink != 0 {
+		link, err := io.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+
+		return x.symlink(file.Name, rel, string(link))
+	}
+
+	if !mode.IsRegular() {
+		return nil
+	}
+
+	return x.file(rel, rc, mode, file.Modified)
+}
+
+// target validates the entry name and returns it as a clean relative path.
+// It returns false for entries skipped by the filters.
+func (x *extractor) target(name string) (string, bool, error) {
+	rel := strings.TrimSuffix(path.Clean(strings.ReplaceAll(name, `\`, "/")), "/")
+
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", false, &fs.PathError{Op: "extract", Path: name, Err: ErrUnsafePath}
+	}
+
+	if rel == "." {
+		return "", false, nil
+	}
+
+	for dir := rel; dir != "."; dir = path.Dir(dir) {
+		if x.opts.excluded(dir) {
+			return "", false, nil
+		}
+	}
+
+	if !x.opts.selected(rel) && !strings.HasSuffix(name, "/") {
+		return "", false, nil
+	}
+
+	return filepath.FromSlash(rel), true, nil
+}
+
+// resolve returns the path of the entry rel inside dst. The parent is
+// resolved with SecureJoin, so links created by earlier entries cannot lead
+// out of dst, while a link at rel itself is kept to be replaced.
+func (x *extractor) resolve(rel string) (string, error) {
+	parent, err := SecureJoin(x.dst, filepath.Dir(rel))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(parent, filepath.Base(rel)), nil
+}
+
+func (x *extractor) dir(rel string, mode fs.FileMode, modTime time.Time) error {
+	target, err := SecureJoin(x.dst, rel)
+	if err != nil {
+		return err
+	}
+
+	// The owner needs write access to fill the directory.
+	if err := os.MkdirAll(target, mode.Perm()|0o700); err != nil {
+		return err
+	}
+
+	x.dirs = append(x.dirs, extractedDir{path: target, mode: mode.Perm(), modTime: modTime})
+
+	return nil
+}
+
+func (x *extractor) file(rel string, r io.Reader, mode fs.FileMode, modTime time.Time) error {
+	target, err := x.prepare(rel)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, &contextReader{ctx: x.ctx, r: r}); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if err := f.Chmod(mode.Perm()); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(target, modTime, modTime)
+}
+
+func (x *extractor) symlink(name, rel, link string) error {
+	// A clean link has ".." elements only in front, which are resolved from
+	// the real parent directory of the entry.
+	link = path.Clean(strings.ReplaceAll(link, `\`, "/"))
+	if path.IsAbs(link) || filepath.IsAbs(link) {
+		return &fs.PathError{Op: "extract", Path: name, Err: ErrUnsafePath}
+	}
+
+	target, err := x.resolve(rel)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := resolvePath(filepath.Join(filepath.Dir(target), filepath.FromSlash(link)))
+	if err != nil {
+		return err
+	}
+
+	if !pathWithin(resolved, x.dst) {
+		return &fs.PathError{Op: "extract", Path: name, Err: ErrUnsafePath}
+	}
+
+	if target, err = x.prepare(rel); err != nil {
+		return err
+	}
+
+	return os.Symlink(filepath.FromSlash(link), target)
+}
+
+func (x *extractor) hardlink(name, rel, link string) error {
+	source := filepath.FromSlash(path.Clean(strings.ReplaceAll(link, `\`, "/")))
+	if !filepath.IsLocal(source) {
+		return &fs.PathError{Op: "extract", Path: name, Err: ErrUnsafePath}
+	}
+
+	source, err := x.resolve(source)
+	if err != nil {
+		return err
+	}
+
+	target, err := x.prepare(rel)
+	if err != nil {
+		return err
+	}
+
+	return os.Link(source, target)
+}
+
+// prepare resolves the entry rel, creates its parent directories and removes
+// an existing file or link at it, so it is never written through.
+func (x *extractor) prepare(rel string) (string, error) {
+	target, err := x.resolve(rel)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), OwnerWritePerm); err != nil {
+		return "", err
+	}
+
+	info, err := os.Lstat(target)
+
+	switch {
+	case err == nil && info.IsDir():
+		return "", &fs.PathError{Op: "extract", Path: target, Err: ErrIsDir}
+	case err == nil:
+		return target, os.Remove(target)
+	case errors.Is(err, fs.ErrNotExist):
+		return target, nil
+	default:
+		return "", err
+	}
+}
+
+// finishDirs applies the directory metadata, children first so setting the
+// times of a parent is not undone by changes inside it.
+func (x *extractor) finishDirs() error {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		dir := x.dirs[i]
+
+		if err := os.Chmod(dir.path, dir.mode); err != nil {
+			return err
+		}
+
+		if err := os.Chtimes(dir.path, dir.modTime, dir.modTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
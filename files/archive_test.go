@@ -0,0 +1,252 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupArchiveTest(t *testing.T) string {
+	t.Helper()
+
+	root := filepath.Join(t.TempDir(), "saves")
+
+	files := map[string]string{
+		"world.dat":        "world",
+		"players/alice.sv": "alice",
+		"players/bob.sv":   "bob",
+		"cache/tmp.bin":    "cache",
+	}
+
+	for name, content := range files {
+		full := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+	}
+
+	require.NoError(t, os.Chmod(filepath.Join(root, "world.dat"), 0o600))
+	require.NoError(t, os.Symlink("players/alice.sv", filepath.Join(root, "current")))
+
+	mtime := time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "world.dat"), mtime, mtime))
+	require.NoError(t, os.Chtimes(filepath.Join(root, "players"), mtime, mtime))
+
+	return root
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	mtime := time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	for _, format := range []ArchiveFormat{FormatTar, FormatTarGz, FormatZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			root := setupArchiveTest(t)
+
+			var buf bytes.Buffer
+			require.NoError(t, CreateArchive(ctx, &buf, root, format, WithExclude("cache")))
+
+			dst := filepath.Join(t.TempDir(), "restored")
+			require.NoError(t, ExtractArchive(ctx, bytes.NewReader(buf.Bytes()), dst, format))
+
+			data, err := os.ReadFile(filepath.Join(dst, "players", "bob.sv"))
+			require.NoError(t, err)
+			assert.Equal(t, "bob", string(data))
+
+			assert.NoDirExists(t, filepath.Join(dst, "cache"))
+
+			info, err := os.Stat(filepath.Join(dst, "world.dat"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+			assert.True(t, info.ModTime().Equal(mtime), info.ModTime())
+
+			info, err = os.Stat(filepath.Join(dst, "players"))
+			require.NoError(t, err)
+			assert.True(t, info.ModTime().Equal(mtime), info.ModTime())
+
+			link, err := os.Readlink(filepath.Join(dst, "current"))
+			require.NoError(t, err)
+			assert.Equal(t, "players/alice.sv", link)
+		})
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	ctx := context.Background()
+	root := setupArchiveTest(t)
+	dir := t.TempDir()
+
+	name := filepath.Join(dir, "backup.tgz")
+	require.NoError(t, CreateArchiveFile(ctx, name, root, WithInclude("*.sv")))
+
+	dst := filepath.Join(dir, "out")
+	require.NoError(t, ExtractArchiveFile(ctx, name, dst))
+
+	entries, err := List(ctx, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"players", "players/alice.sv", "players/bob.sv"}, entryNames(dst, entries))
+
+	t.Run("extract filters", func(t *testing.T) {
+		all := filepath.Join(dir, "all.zip")
+		require.NoError(t, CreateArchiveFile(ctx, all, root))
+
+		out := filepath.Join(dir, "filtered")
+		require.NoError(t, ExtractArchiveFile(ctx, all, out, WithExtractExclude("players")))
+
+		entries, err := List(ctx, out)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"cache", "cache/tmp.bin", "current", "world.dat"}, entryNames(out, entries))
+	})
+
+	t.Run("inside root", func(t *testing.T) {
+		inside := filepath.Join(root, "players", "backup.tar")
+		require.ErrorIs(t, CreateArchiveFile(ctx, inside, root), ErrUnsafePath)
+		assert.NoFileExists(t, inside)
+
+		link := filepath.Join(dir, "root")
+		require.NoError(t, os.Symlink(root, link))
+		assert.ErrorIs(t, CreateArchiveFile(ctx, filepath.Join(link, "backup.tar"), root), ErrUnsafePath)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		assert.ErrorIs(t, CreateArchiveFile(ctx, filepath.Join(dir, "backup.rar"), root), ErrUnknownFormat)
+		assert.ErrorIs(t, ExtractArchiveFile(ctx, filepath.Join(dir, "backup.7z"), dir), ErrUnknownFormat)
+	})
+}
+
+func TestArchiveFormatFromName(t *testing.T) {
+	tests := map[string]ArchiveFormat{
+		"a.tar":    FormatTar,
+		"a.TAR.GZ": FormatTarGz,
+		"a.tgz":    FormatTarGz,
+		"a.zip":    FormatZip,
+	}
+
+	for name, expected := range tests {
+		format, err := ArchiveFormatFromName(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+
+	_, err := ArchiveFormatFromName("a.gz")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestExtractArchiveTraversal(t *testing.T) {
+	ctx := context.Background()
+
+	tarArchive := func(headers ...*tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+
+		tw := tar.NewWriter(&buf)
+		for _, header := range headers {
+			require.NoError(t, tw.WriteHeader(header))
+			if header.Size > 0 {
+				_, err := tw.Write(bytes.Repeat([]byte("x"), int(header.Size)))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tw.Close())
+
+		return &buf
+	}
+
+	tests := map[string]*bytes.Buffer{
+		"parent":           tarArchive(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"nested parent":    tarArchive(&tar.Header{Name: "a/../../evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"absolute":         tarArchive(&tar.Header{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"symlink outside":  tarArchive(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}),
+		"symlink absolute": tarArchive(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}),
+		"hardlink outside": tarArchive(&tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "../secret"}),
+	}
+
+	for name, archive := range tests {
+		t.Run(name, func(t *testing.T) {
+			base := t.TempDir()
+			dst := filepath.Join(base, "dst")
+
+			err := ExtractArchive(ctx, archive, dst, FormatTar)
+			assert.ErrorIs(t, err, ErrUnsafePath)
+			assert.NoFileExists(t, filepath.Join(base, "evil"))
+		})
+	}
+
+	t.Run("zip slip", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("../../evil")
+		require.NoError(t, err)
+		_, err = w.Write([]byte("x"))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		base := t.TempDir()
+		err = ExtractArchive(ctx, &buf, filepath.Join(base, "a", "dst"), FormatZip)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+		assert.NoFileExists(t, filepath.Join(base, "evil"))
+	})
+
+	t.Run("chained symlinks", func(t *testing.T) {
+		archive := tarArchive(
+			&tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			&tar.Header{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			&tar.Header{Name: "b/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		)
+
+		base := t.TempDir()
+		err := ExtractArchive(ctx, archive, filepath.Join(base, "dst"), FormatTar)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+		assert.NoFileExists(t, filepath.Join(base, "evil.txt"))
+	})
+
+	t.Run("symlink inside", func(t *testing.T) {
+		archive := tarArchive(
+			&tar.Header{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 3},
+			&tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../dir/file"},
+			&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "dir/file"},
+		)
+
+		dst := t.TempDir()
+		require.NoError(t, ExtractArchive(ctx, archive, dst, FormatTar))
+
+		data, err := os.ReadFile(filepath.Join(dst, "dir", "link"))
+		require.NoError(t, err)
+		assert.Equal(t, "xxx", string(data))
+
+		data, err = os.ReadFile(filepath.Join(dst, "hard"))
+		require.NoError(t, err)
+		assert.Equal(t, "xxx", string(data))
+	})
+
+	t.Run("existing symlink is replaced", func(t *testing.T) {
+		dst := t.TempDir()
+		outside := filepath.Join(t.TempDir(), "outside")
+		require.NoError(t, os.WriteFile(outside, []byte("keep"), 0o644))
+		require.NoError(t, os.Symlink(outside, filepath.Join(dst, "file")))
+
+		archive := tarArchive(&tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 3})
+		require.NoError(t, ExtractArchive(ctx, archive, dst, FormatTar))
+
+		data, err := os.ReadFile(outside)
+		require.NoError(t, err)
+		assert.Equal(t, "keep", string(data))
+	})
+}
+
+func TestArchiveCanceled(t *testing.T) {
+	root := setupArchiveTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, CreateArchive(ctx, &buf, root, FormatTar), context.Canceled)
+}
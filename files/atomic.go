@@ -0,0 +1,198 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DefaultFilePerm is the permission (before umask) of files created by the
+// atomic writers when no permission is passed.
+const DefaultFilePerm = os.FileMode(0o666)
+
+// tempAttempts is how many random names are tried for a temporary file.
+const tempAttempts = 100
+
+// WriteFileAtomic writes data to filename so that readers and crashes see
+// either the old or the new content, never a partially written file.
+//
+// The data is written to a temporary file in the same directory, flushed to
+// disk and renamed over filename, then the directory itself is flushed so the
+// rename survives a power loss.
+//
+// Behavior details:
+//   - An existing target keeps its permissions and, where the process is
+//     allowed to set them, its owner and group; perms are ignored then
+//   - New files get perms[0] or DefaultFilePerm, masked by the umask
+//   - If filename is a symbolic link, the file it points to is replaced and
+//     the link is kept
+//   - The temporary file is removed on error
+//
+// Example usage:
+//
+//	err := WriteFileAtomic("/etc/myapp/state.json", data, 0o600)
+func WriteFileAtomic(filename string, data []byte, perms ...os.FileMode) error {
+	return WriteFileAtomicReader(filename, bytes.NewReader(data), perms...)
+}
+
+// WriteFileAtomicString is like WriteFileAtomic but writes a string.
+func WriteFileAtomicString(filename string, value string, perms ...os.FileMode) error {
+	return WriteFileAtomicReader(filename, strings.NewReader(value), perms...)
+}
+
+// WriteFileAtomicReader is like WriteFileAtomic but writes everything read
+// from r. The target is left untouched if reading from r fails.
+func WriteFileAtomicReader(filename string, r io.Reader, perms ...os.FileMode) error {
+	perm := DefaultFilePerm
+	if len(perms) != 0 {
+		perm = perms[0]
+	}
+
+	return writeAtomic(filename, perm, func(f *os.File) error {
+		_, err := io.Copy(f, r)
+
+		return err
+	})
+}
+
+// writeAtomic creates a temporary file next to filename, fills it with write
+// and renames it over filename, preserving the mode and owner of an existing
+// target.
+func writeAtomic(filename string, perm os.FileMode, write func(f *os.File) error) (err error) {
+	target, err := resolveTarget(filename)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	dir := filepath.Dir(target)
+
+	tmp, err := createTemp(dir, filepath.Base(target), perm)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// Applied before writing so write may still override the metadata.
+	if info != nil {
+		if err := copyOwnership(tmp, info); err != nil {
+			return err
+		}
+	}
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// createTemp creates a new file for writing in dir with a random name derived
+// from name. Unlike os.CreateTemp it applies perm, so the umask is respected.
+func createTemp(dir, name string, perm os.FileMode) (*os.File, error) {
+	for range tempAttempts {
+		f, err := os.OpenFile(tempName(dir, name), os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+
+		return f, err
+	}
+
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, name), Err: fs.ErrExist}
+}
+
+// tempName returns a random hidden name for a temporary copy of name in dir.
+func tempName(dir, name string) string {
+	return filepath.Join(dir, "."+name+".tmp-"+strconv.FormatUint(uint64(rand.Uint32()), 36))
+}
+
+// copyOwnership applies the mode, owner and group of info to f.
+func copyOwnership(f *os.File, info fs.FileInfo) error {
+	// The owner goes first because chown clears the setuid and setgid bits.
+	if err := chownLike(f.Chown, info); err != nil {
+		return err
+	}
+
+	return f.Chmod(modeBits(info))
+}
+
+// chownLike calls chown with the owner and group of info. Only root may give
+// files away, so the owner is kept when the change is not permitted.
+func chownLike(chown func(uid, gid int) error, info fs.FileInfo) error {
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return nil
+	}
+
+	if err := chown(uid, gid); err != nil && !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+
+	return nil
+}
+
+// modeBits returns the permission and special bits of info.
+func modeBits(info fs.FileInfo) os.FileMode {
+	return info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+}
+
+// resolveTarget follows filename if it is a symbolic link, so the link is
+// preserved and the file it points to is replaced.
+func resolveTarget(filename string) (string, error) {
+	target, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return filename, nil
+		}
+
+		return "", err
+	}
+
+	return target, nil
+}
+
+// syncDir flushes the directory entries of dir to disk. Filesystems not
+// supporting fsync on directories are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+
+	return nil
+}
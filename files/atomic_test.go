@@ -0,0 +1,97 @@
+package files
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	require.NoError(t, err)
+
+	return matches
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("new file", func(t *testing.T) {
+		name := filepath.Join(dir, "new.txt")
+
+		require.NoError(t, WriteFileAtomic(name, []byte("content"), 0o600))
+
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assert.Empty(t, tempFiles(t, dir))
+	})
+
+	t.Run("existing file keeps mode", func(t *testing.T) {
+		name := filepath.Join(dir, "existing.txt")
+		require.NoError(t, os.WriteFile(name, []byte("old content"), 0o640))
+		require.NoError(t, os.Chmod(name, 0o640))
+
+		require.NoError(t, WriteFileAtomicString(name, "new", 0o600))
+
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	})
+
+	t.Run("symlink is kept", func(t *testing.T) {
+		target := filepath.Join(dir, "target.txt")
+		link := filepath.Join(dir, "link.txt")
+		require.NoError(t, os.WriteFile(target, []byte("old"), 0o644))
+		require.NoError(t, os.Symlink("target.txt", link))
+
+		require.NoError(t, WriteFileAtomicReader(link, strings.NewReader("via link")))
+
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		assert.Equal(t, os.ModeSymlink, info.Mode().Type())
+
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		assert.Equal(t, "via link", string(data))
+	})
+
+	t.Run("failed write keeps target", func(t *testing.T) {
+		name := filepath.Join(dir, "keep.txt")
+		require.NoError(t, os.WriteFile(name, []byte("keep me"), 0o644))
+
+		err := WriteFileAtomicReader(name, failingReader{})
+		require.Error(t, err)
+
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, "keep me", string(data))
+		assert.Empty(t, tempFiles(t, dir))
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		err := WriteFileAtomic(filepath.Join(dir, "missing", "file.txt"), []byte("x"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,317 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// copyChunkSize is how much data is copied between context checks and
+// progress reports.
+const copyChunkSize = 4 << 20
+
+// ProgressFunc reports that written of total bytes of the file src have been
+// copied. It is called after every chunk, at least once per file.
+type ProgressFunc func(src string, written, total int64)
+
+// Copy copies the file src to dst without loading it into memory. On Linux
+// the data is copied inside the kernel with copy_file_range(2) when possible.
+//
+// The destination is replaced atomically, see WriteFileAtomic. New files get
+// the permission of src masked by the umask, existing files keep their mode
+// and owner unless asked to preserve the ones of src. Symbolic links are
+// copied as links unless WithFollowSymlinks is set.
+//
+// Supported options: WithPreserveMode, WithPreserveTimes, WithPreserveOwner,
+// WithFollowSymlinks, WithFileMode and WithProgress.
+//
+// Example usage:
+//
+//	err := Copy(ctx, "/backups/db.dump", "/mnt/nfs/db.dump",
+//	    WithPreserveMode(),
+//	    WithPreserveTimes(),
+//	    WithProgress(func(src string, written, total int64) {
+//	        log.Printf("%s: %d/%d", src, written, total)
+//	    }),
+//	)
+func Copy(ctx context.Context, src, dst string, opts ...CopyOption) error {
+	o := newCopyOptions(opts...)
+
+	info, err := o.stat(src)
+	if err != nil {
+		return err
+	}
+
+	switch mode := info.Mode(); {
+	case mode.IsRegular():
+		return copyFile(ctx, src, dst, info, o)
+	case mode&fs.ModeSymlink != 0:
+		return copySymlink(src, dst, info, o)
+	case mode.IsDir():
+		return &fs.PathError{Op: "copy", Path: src, Err: ErrIsDir}
+	default:
+		return &fs.PathError{Op: "copy", Path: src, Err: ErrNotRegular}
+	}
+}
+
+// CopyDir recursively copies the directory src to dst the same way Copy
+// copies files. Existing directories are merged. Devices, sockets and named
+// pipes are skipped.
+//
+// Supported options: those of Copy. Use SyncDir to copy a filtered tree.
+//
+// Behavior details:
+//   - Directory metadata is applied after their content is copied, so
+//     preserved times are not changed by the copy itself
+//   - With WithFollowSymlinks, links leading back to a directory being copied
+//     return ErrSymlinkLoop
+//   - Refuses a dst equal to or inside src, which would copy the copy again,
+//     with ErrUnsafePath
+//   - Stops and returns ctx.Err() as soon as ctx is done
+func CopyDir(ctx context.Context, src, dst string, opts ...CopyOption) error {
+	o := newCopyOptions(opts...)
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return &fs.PathError{Op: "copy", Path: src, Err: ErrNotDir}
+	}
+
+	srcPath, err := resolvePath(src)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := resolvePath(dst)
+	if err != nil {
+		return err
+	}
+
+	if pathWithin(dstPath, srcPath) {
+		return &fs.PathError{Op: "copy", Path: dst, Err: ErrUnsafePath}
+	}
+
+	c := &treeCopier{
+		ctx:     ctx,
+		opts:    o,
+		visited: make(map[fileID]struct{}),
+	}
+
+	return c.copyDir(src, dst, info)
+}
+
+// fileID identifies a file on the host.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// treeCopier holds the state of a CopyDir call.
+type treeCopier struct {
+	ctx     context.Context //nolint:containedctx // scoped to a single copy
+	opts    *copyOptions
+	visited map[fileID]struct{}
+}
+
+func (c *treeCopier) copyDir(src, dst string, info fs.FileInfo) error {
+	if id, ok := fileIdentity(info); ok {
+		if _, ok := c.visited[id]; ok {
+			return &fs.PathError{Op: "copy", Path: src, Err: ErrSymlinkLoop}
+		}
+
+		c.visited[id] = struct{}{}
+		defer delete(c.visited, id)
+	}
+
+	// The owner needs write access to fill the directory.
+	err := os.Mkdir(dst, info.Mode().Perm()|0o700)
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	created := err == nil
+
+	items, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		full := filepath.Join(src, item.Name())
+		target := filepath.Join(dst, item.Name())
+
+		itemInfo, err := c.opts.stat(full)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return err
+		}
+
+		switch mode := itemInfo.Mode(); {
+		case mode.IsDir():
+			err = c.copyDir(full, target, itemInfo)
+		case mode.IsRegular():
+			err = copyFile(c.ctx, full, target, itemInfo, c.opts)
+		case mode&fs.ModeSymlink != 0:
+			err = copySymlink(full, target, itemInfo, c.opts)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return applyDirMetadata(dst, info, c.opts, created)
+}
+
+// stat returns the file info of name following symbolic links if requested.
+func (o *copyOptions) stat(name string) (fs.FileInfo, error) {
+	if o.followSymlinks {
+		return os.Stat(name)
+	}
+
+	return os.Lstat(name)
+}
+
+func copyFile(ctx context.Context, src, dst string, info fs.FileInfo, o *copyOptions) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	perm := info.Mode().Perm()
+	if o.fileMode != 0 {
+		perm = o.fileMode
+	}
+
+	return writeAtomic(dst, perm, func(f *os.File) error {
+		if err := copyContent(ctx, f, in, info.Size(), o.progress); err != nil {
+			return err
+		}
+
+		if o.preserveOwner {
+			if err := chownLike(f.Chown, info); err != nil {
+				return err
+			}
+		}
+
+		if o.preserveMode {
+			if err := f.Chmod(modeBits(info)); err != nil {
+				return err
+			}
+		}
+
+		if o.preserveTimes {
+			return os.Chtimes(f.Name(), accessTime(info), info.ModTime())
+		}
+
+		return nil
+	})
+}
+
+// copyContent copies src to dst in chunks checking ctx in between.
+// io.CopyN keeps the *os.File pair visible to io.Copy, so copy_file_range
+// is still used.
+func copyContent(ctx context.Context, dst, src *os.File, total int64, progress ProgressFunc) error {
+	var written int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := io.CopyN(dst, src, copyChunkSize)
+		written += n
+
+		if progress != nil && (n > 0 || written == 0) {
+			progress(src.Name(), written, total)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// copySymlink recreates the symbolic link src at dst replacing dst
+// atomically. Link times cannot be set portably and are not preserved.
+func copySymlink(src, dst string, info fs.FileInfo, o *copyOptions) error {
+	link, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(dst)
+	tmp := tempName(dir, filepath.Base(dst))
+
+	if err := os.Symlink(link, tmp); err != nil {
+		return err
+	}
+
+	if o.preserveOwner {
+		err = chownLike(func(uid, gid int) error { return os.Lchown(tmp, uid, gid) }, info)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// applyDirMetadata sets the mode, owner and times of the copied directory.
+// A directory created by the copy loses the owner permission bits it got
+// only to be filled, existing directories keep their mode.
+func applyDirMetadata(dst string, info fs.FileInfo, o *copyOptions, created bool) error {
+	if o.preserveOwner {
+		if err := chownLike(func(uid, gid int) error { return os.Lchown(dst, uid, gid) }, info); err != nil {
+			return err
+		}
+	}
+
+	if missing := 0o700 &^ info.Mode().Perm(); o.preserveMode || (created && missing != 0) {
+		mode := modeBits(info)
+
+		if !o.preserveMode {
+			current, err := os.Stat(dst)
+			if err != nil {
+				return err
+			}
+
+			mode = modeBits(current) &^ missing
+		}
+
+		if err := os.Chmod(dst, mode); err != nil {
+			return err
+		}
+	}
+
+	if o.preserveTimes {
+		return os.Chtimes(dst, accessTime(info), info.ModTime())
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	src := filepath.Join(dir, "src.bin")
+	content := strings.Repeat("0123456789", copyChunkSize/10+100)
+	require.NoError(t, os.WriteFile(src, []byte(content), 0o640))
+	require.NoError(t, os.Chmod(src, 0o640))
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	t.Run("content", func(t *testing.T) {
+		dst := filepath.Join(dir, "plain.bin")
+		require.NoError(t, Copy(ctx, src, dst))
+
+		data, err := os.ReadFile(dst)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+
+		info, err := os.Stat(dst)
+		require.NoError(t, err)
+		assert.False(t, info.ModTime().Equal(mtime))
+	})
+
+	t.Run("preserve mode and times", func(t *testing.T) {
+		dst := filepath.Join(dir, "preserved.bin")
+		require.NoError(t, os.WriteFile(dst, []byte("old"), 0o600))
+
+		require.NoError(t, Copy(ctx, src, dst, WithPreserveMode(), WithPreserveTimes(), WithPreserveOwner()))
+
+		info, err := os.Stat(dst)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		assert.True(t, info.ModTime().Equal(mtime))
+	})
+
+	t.Run("existing file keeps mode", func(t *testing.T) {
+		dst := filepath.Join(dir, "existing.bin")
+		require.NoError(t, os.WriteFile(dst, []byte("old"), 0o600))
+
+		require.NoError(t, Copy(ctx, src, dst))
+
+		info, err := os.Stat(dst)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assert.Equal(t, int64(len(content)), info.Size())
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		var calls []int64
+
+		dst := filepath.Join(dir, "progress.bin")
+		err := Copy(ctx, src, dst, WithProgress(func(name string, written, total int64) {
+			assert.Equal(t, src, name)
+			assert.Equal(t, int64(len(content)), total)
+			calls = append(calls, written)
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{copyChunkSize, int64(len(content))}, calls)
+	})
+
+	t.Run("empty file progress", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty")
+		require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+		calls := 0
+		require.NoError(t, Copy(ctx, empty, filepath.Join(dir, "empty.copy"), WithProgress(func(string, int64, int64) {
+			calls++
+		})))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("symlinks", func(t *testing.T) {
+		link := filepath.Join(dir, "link")
+		require.NoError(t, os.Symlink("src.bin", link))
+
+		dst := filepath.Join(dir, "link.copy")
+		require.NoError(t, Copy(ctx, link, dst))
+
+		target, err := os.Readlink(dst)
+		require.NoError(t, err)
+		assert.Equal(t, "src.bin", target)
+
+		followed := filepath.Join(dir, "followed.copy")
+		require.NoError(t, Copy(ctx, link, followed, WithFollowSymlinks()))
+
+		info, err := os.Lstat(followed)
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular())
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		dst := filepath.Join(dir, "canceled.bin")
+		assert.ErrorIs(t, Copy(canceled, src, dst), context.Canceled)
+		assert.NoFileExists(t, dst)
+		assert.Empty(t, tempFiles(t, dir))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.ErrorIs(t, Copy(ctx, dir, filepath.Join(dir, "x")), ErrIsDir)
+		assert.ErrorIs(t, Copy(ctx, filepath.Join(dir, "missing"), filepath.Join(dir, "x")), os.ErrNotExist)
+	})
+}
+
+func TestCopyDir(t *testing.T) {
+	ctx := context.Background()
+	src := filepath.Join(t.TempDir(), "src")
+
+	files := map[string]string{
+		"a.txt":          "a",
+		"sub/b.txt":      "bb",
+		"sub/c.log":      "ccc",
+		"sub/deep/d.txt": "dddd",
+		"skip/e.txt":     "eeeee",
+	}
+
+	for name, content := range files {
+		full := filepath.Join(src, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+	}
+
+	require.NoError(t, os.Symlink("../a.txt", filepath.Join(src, "sub", "a-link")))
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chmod(filepath.Join(src, "sub"), 0o750))
+	require.NoError(t, os.Chtimes(filepath.Join(src, "sub"), mtime, mtime))
+
+	t.Run("full copy", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "dst")
+		require.NoError(t, CopyDir(ctx, src, dst, WithPreserveMode(), WithPreserveTimes()))
+
+		for name, content := range files {
+			data, err := os.ReadFile(filepath.Join(dst, name))
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+		}
+
+		link, err := os.Readlink(filepath.Join(dst, "sub", "a-link"))
+		require.NoError(t, err)
+		assert.Equal(t, "../a.txt", link)
+
+		info, err := os.Stat(filepath.Join(dst, "sub"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+		assert.True(t, info.ModTime().Equal(mtime))
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		var copied []string
+
+		dst := filepath.Join(t.TempDir(), "dst")
+		require.NoError(t, CopyDir(ctx, src, dst, WithProgress(func(name string, _, _ int64) {
+			rel, _ := filepath.Rel(src, name)
+			copied = append(copied, rel)
+		})))
+
+		assert.ElementsMatch(t, []string{"a.txt", "skip/e.txt", "sub/b.txt", "sub/c.log", "sub/deep/d.txt"}, copied)
+	})
+
+	t.Run("symlink loop", func(t *testing.T) {
+		loop := filepath.Join(t.TempDir(), "loop")
+		require.NoError(t, os.MkdirAll(filepath.Join(loop, "dir"), 0o755))
+		require.NoError(t, os.Symlink("..", filepath.Join(loop, "dir", "parent")))
+
+		err := CopyDir(ctx, loop, filepath.Join(t.TempDir(), "dst"), WithFollowSymlinks())
+		assert.ErrorIs(t, err, ErrSymlinkLoop)
+	})
+
+	t.Run("default directory mode", func(t *testing.T) {
+		readOnly := filepath.Join(t.TempDir(), "ro")
+		require.NoError(t, os.MkdirAll(filepath.Join(readOnly, "empty"), 0o755))
+		require.NoError(t, os.Chmod(filepath.Join(readOnly, "empty"), 0o555))
+
+		dst := filepath.Join(t.TempDir(), "dst")
+		require.NoError(t, CopyDir(ctx, readOnly, dst))
+
+		info, err := os.Stat(filepath.Join(dst, "empty"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o555), info.Mode().Perm())
+	})
+
+	t.Run("destination inside source", func(t *testing.T) {
+		err := CopyDir(ctx, src, filepath.Join(src, "sub", "backup"))
+		assert.ErrorIs(t, err, ErrUnsafePath)
+		assert.NoDirExists(t, filepath.Join(src, "sub", "backup"))
+
+		assert.ErrorIs(t, CopyDir(ctx, src, src), ErrUnsafePath)
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		err := CopyDir(ctx, filepath.Join(src, "a.txt"), filepath.Join(t.TempDir(), "dst"))
+		assert.ErrorIs(t, err, ErrNotDir)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := CopyDir(canceled, src, filepath.Join(t.TempDir(), "dst"))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyUmask(t *testing.T) {
+	old := syscall.Umask(0o022)
+	defer syscall.Umask(old)
+
+	dir := t.TempDir()
+
+	src := filepath.Join(dir, "src.bin")
+	require.NoError(t, os.WriteFile(src, []byte("content"), 0o640))
+	require.NoError(t, os.Chmod(src, 0o640))
+
+	dst := filepath.Join(dir, "dst.bin")
+	require.NoError(t, Copy(context.Background(), src, dst))
+
+	info, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+}
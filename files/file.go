@@ -1,47 +1,78 @@
 package files
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 )
 
 // OwnerWritePerm provides 0755 permission.
 const OwnerWritePerm = os.FileMode(0o755)
 
+// Common error definitions used throughout the package.
+var (
+	// ErrNotDir indicates that a path expected to be a directory is not one.
+	ErrNotDir = errors.New("not a directory")
+
+	// ErrIsDir indicates that a path expected to be a file is a directory.
+	ErrIsDir = errors.New("is a directory")
+
+	// ErrNotRegular indicates that a file is neither a regular file, a
+	// directory nor a symbolic link, e.g. a device or a socket.
+	ErrNotRegular = errors.New("not a regular file")
+
+	// ErrSymlinkLoop indicates that following symbolic links leads back to
+	// a directory being processed.
+	ErrSymlinkLoop = errors.New("symbolic link loop")
+
+	// ErrUnsafePath indicates that an operation was refused because of its
+	// target, like a destructive operation on the filesystem root or the home
+	// directory, or a path leading out of its root directory.
+	ErrUnsafePath = errors.New("unsafe path")
+)
+
 // FileExists checks if a file exists and is not a directory before we
 // try using it to prevent further errors.
 func FileExists(filename string) bool {
 	info, err := os.Stat(filename)
-	if os.IsNotExist(err) {
+	if err != nil {
 		return false
 	}
 
 	return !info.IsDir()
 }
 
-// FileCopy copies src file to destination path.
+// DirExists checks if a directory exists, following symbolic links.
+func DirExists(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return info.IsDir()
+}
+
+// FileCopy copies src file to destination path. The file is streamed and the
+// destination is replaced atomically, see Copy. A symbolic link src is
+// followed and the file it points to is copied.
 func FileCopy(src string, destination string, perms ...os.FileMode) error {
 	perm := os.ModePerm
 	if len(perms) != 0 {
 		perm = perms[0]
 	}
 
-	input, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(destination, input, perm)
+	return Copy(context.Background(), src, destination, WithFileMode(perm), WithFollowSymlinks())
 }
 
-// ReadStringFile reads file as string.
+// ReadStringFile reads file as string. The name must stay inside path,
+// see SecureJoin.
 func ReadStringFile(path string, name string) (string, error) {
-	b, err := os.ReadFile(path + "/" + name)
+	b, err := ReadBinFile(path, name)
 	if err != nil {
 		return "", err
 	}
@@ -49,9 +80,15 @@ func ReadStringFile(path string, name string) (string, error) {
 	return string(b), nil
 }
 
-// ReadBinFile reads file as slice of bytes.
+// ReadBinFile reads file as slice of bytes. The name must stay inside path,
+// see SecureJoin.
 func ReadBinFile(path string, name string) ([]byte, error) {
-	b, err := os.ReadFile(path + "/" + name)
+	filename, err := SecureJoin(path, name)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	b, err := os.ReadFile(filename)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -59,18 +96,16 @@ func ReadBinFile(path string, name string) ([]byte, error) {
 	return b, nil
 }
 
-// WriteFileString writes string content to text file.
+// WriteFileString writes string content to text file. The file is replaced
+// atomically, see WriteFileAtomic. The name must stay inside path, see
+// SecureJoin.
 func WriteFileString(path string, name string, value string) error {
-	fo, err := os.Create(path + "/" + name)
+	filename, err := SecureJoin(path, name)
 	if err != nil {
 		return err
 	}
 
-	if _, err := fo.WriteString(value); err != nil {
-		return err
-	}
-
-	return nil
+	return WriteFileAtomicString(filename, value)
 }
 
 // CreateAndOpenFile creates file and open it foe recording.
@@ -100,19 +135,16 @@ func CreateAndOpenFile(path string, fileName string, perms ...os.FileMode) (io.W
 	return f, nil
 }
 
-// StatTimes gets file stats info.
+// StatTimes gets file stats info. It returns ErrStatUnsupported on platforms
+// not exposing access and change times, see Stat. Unlike Stat it does not
+// look up the owner names.
 func StatTimes(name string) (atime, mtime, ctime time.Time, err error) {
-	info, err := os.Stat(name)
+	info, err := statFile(name, true)
 	if err != nil {
 		return
 	}
 
-	mtime = info.ModTime()
-	stat := info.Sys().(*syscall.Stat_t) //nolint
-	atime = time.Unix(stat.Atim.Sec, stat.Atim.Nsec)
-	ctime = time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)
-
-	return
+	return info.AccessTime, info.ModTime, info.ChangeTime, nil
 }
 
 // MkdirAll creates a directory named path,
@@ -173,10 +205,16 @@ func GetFileNamesInFolder(path string) ([]string, error) {
 
 // GetAbsPath returns an absolute path based on the input path and a default path.
 // It handles three cases for the input path:
-//  1. If the path is already absolute (starts with "/"), home-relative (starts with "~/"),
-//     or relative to current directory (starts with "./"), it returns the path as-is.
-//  2. If the path is empty, it uses the defaultPath instead.
-//  3. For all other cases, it treats the path as relative to the executable's directory.
+//  1. If the path is empty, it uses the defaultPath instead.
+//  2. A leading "~" or "~user" is expanded to the home directory. Environment
+//     variables are not expanded, use ExpandPath or ResolvePath for that.
+//  3. Absolute paths and paths relative to the current directory (".", "..",
+//     or starting with "./" or "../") are returned as-is, all other paths
+//     are treated as relative to the executable's directory.
+//
+// The executable's directory is the one of os.Args[0], so a binary started
+// through a symbolic link resolves paths next to the link. Use ResolvePath
+// with BaseExecutable to resolve them next to the real binary instead.
 //
 // Parameters:
 //   - path: The input path to process (can be empty, absolute, or relative)
@@ -184,33 +222,43 @@ func GetFileNamesInFolder(path string) ([]string, error) {
 //
 // Returns:
 //   - string: The resulting absolute path
-//   - error: Any error that occurred while getting the executable's directory
+//   - error: Any error that occurred while looking up the home directory or
+//     getting the executable's directory
 //
 // Example usage:
 //
 //	absPath, err := GetAbsPath("config.json", "/etc/default/config.json")
 //	// Returns "/path/to/executable/config.json" if no error
+//
+//	absPath, err := GetAbsPath("~/config.json", "")
+//	// Returns "/home/user/config.json" if no error
+//
+// Use ResolvePath to resolve relative paths against another base directory.
 func GetAbsPath(path, defaultPath string) (string, error) {
 	// Use default path if input path is empty
 	if path == "" {
 		path = defaultPath
 	}
 
-	// Check if path is already in absolute, home-relative, or current-directory-relative form
-	if path != "" && (string(path[0]) == "/" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "./")) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if path is already in absolute or current-directory-relative form
+	if filepath.IsAbs(path) || path == "." || path == ".." ||
+		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") {
 		return path, nil
 	}
 
 	// Get the absolute path of the directory containing the executable
-	home, err := filepath.Abs(filepath.Dir(os.Args[0]))
+	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
 	if err != nil {
 		return "", err
 	}
 
 	// Combine executable directory with the relative path
-	path = home + "/" + path
-
-	return path, nil
+	return filepath.Join(dir, path), nil
 }
 
 // ClearDir removes all contents of the specified directory while preserving
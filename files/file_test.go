@@ -44,6 +44,25 @@ func TestFileExists(t *testing.T) {
 
 		assert.False(t, FileExists(dir))
 	})
+
+	t.Run("path through a file", func(t *testing.T) {
+		tmpfile, err := os.CreateTemp(TestFilesDir, "testfile")
+		require.NoError(t, err)
+		defer os.Remove(tmpfile.Name())
+
+		assert.False(t, FileExists(filepath.Join(tmpfile.Name(), "file")))
+	})
+}
+
+func TestDirExists(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, []byte("test"), 0o644))
+
+	assert.True(t, DirExists(dir))
+	assert.False(t, DirExists(file))
+	assert.False(t, DirExists(filepath.Join(dir, "missing")))
+	assert.False(t, DirExists(filepath.Join(file, "dir")))
 }
 
 func TestFileCopy(t *testing.T) {
@@ -67,6 +86,25 @@ func TestFileCopy(t *testing.T) {
 		assert.Equal(t, "test content", string(content))
 	})
 
+	t.Run("symlink source", func(t *testing.T) {
+		dir, err := os.MkdirTemp(TestFilesDir, "testdir")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "target.txt"), []byte("linked"), 0644))
+		require.NoError(t, os.Symlink("target.txt", filepath.Join(dir, "link")))
+
+		dst := filepath.Join(TestFilesDir, "dstfile")
+		defer os.Remove(dst)
+
+		err = FileCopy(filepath.Join(dir, "link"), dst)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(dst)
+		require.NoError(t, err)
+		assert.Equal(t, "linked", string(content))
+	})
+
 	t.Run("nonexistent source", func(t *testing.T) {
 		err := FileCopy("/nonexistent/src", "/tmp/dst")
 		assert.Error(t, err)
@@ -110,6 +148,15 @@ func TestReadStringFile(t *testing.T) {
 		_, err := ReadStringFile("/nonexistent", "file.txt")
 		assert.Error(t, err)
 	})
+
+	t.Run("name escaping path", func(t *testing.T) {
+		dir, err := os.MkdirTemp(TestFilesDir, "testdir")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		_, err = ReadStringFile(dir, "../../etc/passwd")
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
 }
 
 func TestReadBinFile(t *testing.T) {
@@ -196,6 +243,16 @@ func TestCreateAndOpenFile(t *testing.T) {
 		_, err := CreateAndOpenFile("/nonexistent/path", "test.txt")
 		assert.Error(t, err)
 	})
+
+	t.Run("empty path with absolute file name", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "test.log")
+
+		writer, err := CreateAndOpenFile("", name)
+		require.NoError(t, err)
+		writer.(io.Closer).Close()
+
+		assert.FileExists(t, name)
+	})
 }
 
 func TestStatTimes(t *testing.T) {
@@ -324,10 +381,12 @@ func TestGetAbsPath(t *testing.T) {
 	})
 
 	t.Run("home-relative path", func(t *testing.T) {
-		path := "~/relative/path"
-		result, err := GetAbsPath(path, "")
+		home, err := os.UserHomeDir()
 		require.NoError(t, err)
-		assert.Equal(t, path, result)
+
+		result, err := GetAbsPath("~/relative/path", "")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(home, "relative/path"), result)
 	})
 
 	t.Run("current-dir-relative path", func(t *testing.T) {
@@ -337,6 +396,22 @@ func TestGetAbsPath(t *testing.T) {
 		assert.Equal(t, path, result)
 	})
 
+	t.Run("parent-dir-relative path", func(t *testing.T) {
+		path := "../relative/path"
+		result, err := GetAbsPath(path, "")
+		require.NoError(t, err)
+		assert.Equal(t, path, result)
+	})
+
+	t.Run("dollar sign kept", func(t *testing.T) {
+		t.Setenv("GOLIBS_APP", "bot")
+
+		path := "/srv/$GOLIBS_APP/data"
+		result, err := GetAbsPath(path, "")
+		require.NoError(t, err)
+		assert.Equal(t, path, result)
+	})
+
 	t.Run("empty path with default", func(t *testing.T) {
 		defaultPath := "/default/path"
 		result, err := GetAbsPath("", defaultPath)
@@ -352,6 +427,22 @@ func TestGetAbsPath(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, filepath.Join(exeDir, "relative/path"), result)
 	})
+
+	t.Run("executable symlink", func(t *testing.T) {
+		exe, err := os.Executable()
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		link := filepath.Join(dir, "bin")
+		require.NoError(t, os.Symlink(exe, link))
+
+		defer func(arg string) { os.Args[0] = arg }(os.Args[0])
+		os.Args[0] = link
+
+		result, err := GetAbsPath("relative/path", "")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "relative/path"), result)
+	})
 }
 
 func TestClearDir(t *testing.T) {
@@ -0,0 +1,517 @@
+package files
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"  //nolint:gosec // checksums, not security
+	"crypto/sha1" //nolint:gosec // checksums, not security
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	// ErrUnknownHash is returned for an unsupported HashAlgorithm.
+	ErrUnknownHash = errors.New("unknown hash algorithm")
+
+	// ErrInvalidManifest is returned by VerifyManifest for a malformed line.
+	ErrInvalidManifest = errors.New("invalid checksum manifest")
+)
+
+// HashAlgorithm is a checksum algorithm supported by the hash helpers.
+type HashAlgorithm int
+
+const (
+	// HashSHA256 is SHA-256, the format of sha256sum.
+	HashSHA256 HashAlgorithm = iota
+
+	// HashSHA1 is SHA-1, the format of sha1sum.
+	HashSHA1
+
+	// HashMD5 is MD5, the format of md5sum.
+	HashMD5
+
+	// HashCRC32 is the IEEE CRC-32 used by gzip and zip. It is fast but only
+	// suitable for detecting accidental corruption.
+	HashCRC32
+)
+
+// String returns the name of the algorithm.
+func (a HashAlgorithm) String() string {
+	switch a {
+	case HashSHA256:
+		return "sha256"
+	case HashSHA1:
+		return "sha1"
+	case HashMD5:
+		return "md5"
+	case HashCRC32:
+		return "crc32"
+	default:
+		return fmt.Sprintf("HashAlgorithm(%d)", int(a))
+	}
+}
+
+// New returns a new hash.Hash computing the algorithm.
+func (a HashAlgorithm) New() (hash.Hash, error) {
+	switch a {
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil //nolint:gosec // checksums, not security
+	case HashMD5:
+		return md5.New(), nil //nolint:gosec // checksums, not security
+	case HashCRC32:
+		return crc32.NewIEEE(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHash, a)
+	}
+}
+
+// Checksum is the hash of a file of a tree.
+type Checksum struct {
+	// Path is the slash separated path relative to the hashed root.
+	Path string
+
+	// Sum is the lowercase hex encoded hash.
+	Sum string
+}
+
+// HashReader returns the hex encoded hash of everything read from r.
+// Reading stops with an error once ctx is done.
+func HashReader(ctx context.Context, r io.Reader, algo HashAlgorithm) (string, error) {
+	h, err := algo.New()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(h, &contextReader{ctx: ctx, r: r}); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFile returns the hex encoded hash of the content of the file name.
+// The file is streamed, so its size does not matter.
+func HashFile(ctx context.Context, name string, algo HashAlgorithm) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return HashReader(ctx, f, algo)
+}
+
+// HashTree hashes the regular files under root and returns their checksums
+// sorted by path.
+//
+// Supported options: WithInclude, WithExclude, WithRegexp, WithMinSize,
+// WithMinAge, WithMaxAge, WithMaxDepth, WithSkipHidden and WithConcurrency.
+// WithConcurrency also sets how many files are hashed in parallel.
+// Symbolic links are not followed.
+func HashTree(ctx context.Context, root string, algo HashAlgorithm, opts ...WalkOption) ([]Checksum, error) {
+	if _, err := algo.New(); err != nil {
+		return nil, err
+	}
+
+	o := newWalkOptions(opts...)
+
+	entries, err := collectFiles(ctx, root, o)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, sums, err := hashEntries(ctx, entries, algo, o.concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make([]Checksum, len(entries))
+
+	for i, entry := range entries {
+		rel, err := filepath.Rel(root, entry.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		checksums[i] = Checksum{Path: filepath.ToSlash(rel), Sum: sums[i]}
+	}
+
+	sort.Slice(checksums, func(i, j int) bool { return checksums[i].Path < checksums[j].Path })
+
+	return checksums, nil
+}
+
+// WriteManifest hashes the tree under root and writes a manifest in the
+// format of sha256sum and friends, one "<sum>  <path>" line per file, so it
+// can be checked with `sha256sum -c` from root as well as with
+// VerifyManifest. It takes the options of HashTree.
+//
+// Example usage:
+//
+//	f, err := os.Create("/var/backups/2024-05-01.sha256")
+//	...
+//	err = WriteManifest(ctx, f, "/var/backups/2024-05-01", HashSHA256)
+func WriteManifest(ctx context.Context, w io.Writer, root string, algo HashAlgorithm, opts ...WalkOption) error {
+	checksums, err := HashTree(ctx, root, algo, opts...)
+	if err != nil {
+		return err
+	}
+
+	return WriteChecksums(w, checksums)
+}
+
+// WriteChecksums writes checksums in the format of sha256sum. Names with
+// a backslash or a line break are escaped the way coreutils does it.
+func WriteChecksums(w io.Writer, checksums []Checksum) error {
+	bw := bufio.NewWriter(w)
+
+	for _, checksum := range checksums {
+		name := checksum.Path
+		prefix := ""
+
+		if strings.ContainsAny(name, "\\\n\r") {
+			prefix = "\\"
+			name = manifestEscaper.Replace(name)
+		}
+
+		if _, err := fmt.Fprintf(bw, "%s%s  %s\n", prefix, checksum.Sum, name); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+var (
+	manifestEscaper   = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+	manifestUnescaper = strings.NewReplacer("\\\\", "\\", "\\n", "\n", "\\r", "\r")
+)
+
+// VerifyResult is the outcome of VerifyManifest. Paths are as written in the
+// manifest.
+type VerifyResult struct {
+	// Matched holds the files whose content matches the manifest.
+	Matched []string
+
+	// Mismatched holds the files whose content differs from the manifest.
+	Mismatched []string
+
+	// Missing holds the files listed in the manifest but not readable.
+	Missing []string
+}
+
+// OK reports whether every file of the manifest matched.
+func (r *VerifyResult) OK() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0
+}
+
+// VerifyManifest checks the files listed in a manifest read from r against
+// their checksums, the same way `sha256sum -c` does.
+//
+// Parameters:
+//   - ctx: stops the verification when done
+//   - r: manifest in the format of WriteManifest or sha256sum
+//   - root: directory relative paths of the manifest are resolved against
+//   - algo: algorithm the manifest was written with
+//
+// Returns:
+//   - *VerifyResult: matched, mismatched and missing files
+//   - error: ErrInvalidManifest for a malformed line, read or hash error
+//
+// Behavior details:
+//   - Both the text ("<sum>  <path>") and binary ("<sum> *<path>") markers
+//     are accepted, hashes may be upper or lower case
+//   - Empty lines and lines starting with "#" are skipped
+//   - A file that does not exist or is not readable is reported as missing
+//     instead of failing the verification
+//
+// Example usage:
+//
+//	res, err := VerifyManifest(ctx, manifest, "/var/backups/2024-05-01", HashSHA256)
+//	if err == nil && !res.OK() {
+//	    log.Printf("corrupted: %v, missing: %v", res.Mismatched, res.Missing)
+//	}
+func VerifyManifest(ctx context.Context, r io.Reader, root string, algo HashAlgorithm) (*VerifyResult, error) {
+	h, err := algo.New()
+	if err != nil {
+		return nil, err
+	}
+
+	sumLen := hex.EncodedLen(h.Size())
+	result := &VerifyResult{
+		Matched:    make([]string, 0),
+		Mismatched: make([]string, 0),
+		Missing:    make([]string, 0),
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		checksum, ok, err := parseManifestLine(scanner.Text(), sumLen)
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		name := filepath.FromSlash(checksum.Path)
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(root, name)
+		}
+
+		sum, err := HashFile(ctx, name, algo)
+
+		switch {
+		case ctx.Err() != nil:
+			return result, ctx.Err()
+		case errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EISDIR):
+			result.Missing = append(result.Missing, checksum.Path)
+		case err != nil:
+			return result, err
+		case strings.EqualFold(sum, checksum.Sum):
+			result.Matched = append(result.Matched, checksum.Path)
+		default:
+			result.Mismatched = append(result.Mismatched, checksum.Path)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// parseManifestLine parses a manifest line with a hash of sumLen characters.
+// It returns false for lines to skip.
+func parseManifestLine(line string, sumLen int) (Checksum, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return Checksum{}, false, nil
+	}
+
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	// The hash is followed by a space and a " " or "*" mode marker.
+	if len(line) < sumLen+3 || line[sumLen] != ' ' || (line[sumLen+1] != ' ' && line[sumLen+1] != '*') {
+		return Checksum{}, false, ErrInvalidManifest
+	}
+
+	sum := line[:sumLen]
+	if _, err := hex.DecodeString(sum); err != nil {
+		return Checksum{}, false, ErrInvalidManifest
+	}
+
+	name := line[sumLen+2:]
+	if escaped {
+		name = manifestUnescaper.Replace(name)
+	}
+
+	return Checksum{Path: name, Sum: strings.ToLower(sum)}, true, nil
+}
+
+// FindDuplicates returns groups of regular files under root with identical
+// content. Files are grouped by size first and only files sharing a size are
+// hashed, so most of the tree is never read.
+//
+// Supported options: WithInclude, WithExclude, WithRegexp, WithMinSize,
+// WithMinAge, WithMaxAge, WithMaxDepth, WithSkipHidden and WithConcurrency.
+// WithConcurrency also sets how many files are hashed in parallel.
+//
+// Behavior details:
+//   - Empty files are skipped, they are all identical
+//   - Groups are sorted by the space they waste, largest first, files
+//     within a group by path
+//   - Hard links to the same file are reported as duplicates as well,
+//     removing them does not free any space
+//   - Use HashSHA256 unless the tree is trusted, files crafted to collide
+//     under MD5, SHA-1 or CRC-32 would be reported as duplicates
+//
+// Example usage:
+//
+//	groups, err := FindDuplicates(ctx, "/srv/media", HashSHA256, WithMinSize(1<<20))
+//	for _, group := range groups {
+//	    // group[1:] can be replaced with links to group[0].
+//	}
+func FindDuplicates(ctx context.Context, root string, algo HashAlgorithm, opts ...WalkOption) ([][]Entry, error) {
+	if _, err := algo.New(); err != nil {
+		return nil, err
+	}
+
+	o := newWalkOptions(opts...)
+
+	entries, err := collectFiles(ctx, root, o)
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]Entry)
+
+	for _, entry := range entries {
+		if entry.Size > 0 {
+			bySize[entry.Size] = append(bySize[entry.Size], entry)
+		}
+	}
+
+	candidates := make([]Entry, 0)
+
+	for _, group := range bySize {
+		if len(group) > 1 {
+			candidates = append(candidates, group...)
+		}
+	}
+
+	candidates, sums, err := hashEntries(ctx, candidates, algo, o.concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	// The size is part of the key, so files of different sizes never share
+	// a group even if their hashes collide.
+	type key struct {
+		size int64
+		sum  string
+	}
+
+	byHash := make(map[key][]Entry)
+
+	for i, entry := range candidates {
+		k := key{size: entry.Size, sum: sums[i]}
+		byHash[k] = append(byHash[k], entry)
+	}
+
+	groups := make([][]Entry, 0)
+
+	for _, group := range byHash {
+		if len(group) > 1 {
+			SortEntries(group, SortName, false)
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a := groups[i][0].Size * int64(len(groups[i])-1)
+		b := groups[j][0].Size * int64(len(groups[j])-1)
+
+		if a != b {
+			return a > b
+		}
+
+		return groups[i][0].Path < groups[j][0].Path
+	})
+
+	return groups, nil
+}
+
+// collectFiles returns the regular files under root matching o.
+func collectFiles(ctx context.Context, root string, o *walkOptions) ([]Entry, error) {
+	var (
+		mu      sync.Mutex
+		entries = make([]Entry, 0)
+	)
+
+	err := walkFiles(ctx, root, o, func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// hashEntries hashes entries with up to n goroutines. It returns the hashed
+// entries and their sums in the order of entries, files vanished since the
+// walk are left out.
+func hashEntries(ctx context.Context, entries []Entry, algo HashAlgorithm, n int) ([]Entry, []string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		sums  = make([]string, len(entries))
+		found = make([]bool, len(entries))
+		jobs  = make(chan int)
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+
+	for range max(n, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				sum, err := HashFile(ctx, entries[i].Path, algo)
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+
+				if err != nil {
+					once.Do(func() {
+						first = err
+						cancel()
+					})
+
+					continue
+				}
+
+				sums[i], found[i] = sum, true
+			}
+		}()
+	}
+
+	for i := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if first != nil {
+		return nil, nil, first
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	hashed := make([]Entry, 0, len(entries))
+	hashedSums := make([]string, 0, len(entries))
+
+	for i, entry := range entries {
+		if found[i] {
+			hashed = append(hashed, entry)
+			hashedSums = append(hashedSums, sums[i])
+		}
+	}
+
+	return hashed, hashedSums, nil
+}
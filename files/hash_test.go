@@ -0,0 +1,199 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupHashTest(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	files := map[string]string{
+		"a.txt":         "hello\n",
+		"b.txt":         "hello\n",
+		"sub/c.txt":     "hello\n",
+		"sub/d.bin":     "world\n",
+		"sub/e.bin":     "other\n",
+		"big/one.dat":   "duplicate content",
+		"big/two.dat":   "duplicate content",
+		"empty1":        "",
+		"empty2":        "",
+		"sub/line\nbrk": "escaped\n",
+	}
+
+	for name, content := range files {
+		full := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+	}
+
+	return root
+}
+
+func TestHashFile(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "hello.txt")
+	require.NoError(t, os.WriteFile(name, []byte("hello\n"), 0o644))
+
+	tests := map[HashAlgorithm]string{
+		HashSHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		HashSHA1:   "f572d396fae9206628714fb2ce00f72e94f2258f",
+		HashMD5:    "b1946ac92492d2347c6235b4d2611184",
+		HashCRC32:  "363a3020",
+	}
+
+	for algo, expected := range tests {
+		t.Run(algo.String(), func(t *testing.T) {
+			sum, err := HashFile(ctx, name, algo)
+			require.NoError(t, err)
+			assert.Equal(t, expected, sum)
+		})
+	}
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		_, err := HashFile(ctx, name, HashAlgorithm(42))
+		assert.ErrorIs(t, err, ErrUnknownHash)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := HashFile(ctx, name+".missing", HashSHA256)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := HashReader(ctx, strings.NewReader("hello"), HashSHA256)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestHashTree(t *testing.T) {
+	root := setupHashTest(t)
+
+	checksums, err := HashTree(context.Background(), root, HashMD5, WithInclude("*.txt"), WithConcurrency(4))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Checksum{
+		{Path: "a.txt", Sum: "b1946ac92492d2347c6235b4d2611184"},
+		{Path: "b.txt", Sum: "b1946ac92492d2347c6235b4d2611184"},
+		{Path: "sub/c.txt", Sum: "b1946ac92492d2347c6235b4d2611184"},
+	}, checksums)
+}
+
+func TestManifest(t *testing.T) {
+	ctx := context.Background()
+	root := setupHashTest(t)
+
+	var manifest bytes.Buffer
+	require.NoError(t, WriteManifest(ctx, &manifest, root, HashSHA256))
+	assert.Contains(t, manifest.String(), "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  a.txt\n")
+	assert.Contains(t, manifest.String(), "\n\\")
+	assert.Contains(t, manifest.String(), "  sub/line\\nbrk\n")
+
+	t.Run("sha256sum compatible", func(t *testing.T) {
+		if _, err := exec.LookPath("sha256sum"); err != nil {
+			t.Skip("sha256sum is not available")
+		}
+
+		cmd := exec.Command("sha256sum", "-c", "--quiet", "-")
+		cmd.Dir = root
+		cmd.Stdin = bytes.NewReader(manifest.Bytes())
+
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		res, err := VerifyManifest(ctx, bytes.NewReader(manifest.Bytes()), root, HashSHA256)
+		require.NoError(t, err)
+		assert.True(t, res.OK())
+		assert.Len(t, res.Matched, 10)
+		assert.Contains(t, res.Matched, "sub/line\nbrk")
+	})
+
+	t.Run("corruption", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, CopyDir(ctx, root, dir))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed\n"), 0o644))
+		require.NoError(t, os.Remove(filepath.Join(dir, "sub", "d.bin")))
+
+		res, err := VerifyManifest(ctx, bytes.NewReader(manifest.Bytes()), dir, HashSHA256)
+		require.NoError(t, err)
+		assert.False(t, res.OK())
+		assert.Equal(t, []string{"a.txt"}, res.Mismatched)
+		assert.Equal(t, []string{"sub/d.bin"}, res.Missing)
+		assert.Len(t, res.Matched, 8)
+	})
+
+	t.Run("external format", func(t *testing.T) {
+		input := "# comment\n\n" +
+			"B1946AC92492D2347C6235B4D2611184 *a.txt\r\n" +
+			"b1946ac92492d2347c6235b4d2611184  " + filepath.Join(root, "sub", "c.txt") + "\n"
+
+		res, err := VerifyManifest(ctx, strings.NewReader(input), root, HashMD5)
+		require.NoError(t, err)
+		assert.True(t, res.OK())
+		assert.Len(t, res.Matched, 2)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, input := range []string{
+			"b1946ac92492d2347c6235b4d2611184 a.txt\n",
+			"b1946ac92492d2347c6235b4d26111  a.txt\n",
+			"z1946ac92492d2347c6235b4d2611184  a.txt\n",
+		} {
+			_, err := VerifyManifest(ctx, strings.NewReader(input), root, HashMD5)
+			assert.ErrorIs(t, err, ErrInvalidManifest, input)
+		}
+	})
+}
+
+func TestFindDuplicates(t *testing.T) {
+	ctx := context.Background()
+	root := setupHashTest(t)
+
+	groups, err := FindDuplicates(ctx, root, HashSHA256, WithConcurrency(3))
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, []string{"big/one.dat", "big/two.dat"}, entryNames(root, groups[0]))
+	assert.Equal(t, []string{"a.txt", "b.txt", "sub/c.txt"}, entryNames(root, groups[1]))
+
+	t.Run("filters", func(t *testing.T) {
+		groups, err := FindDuplicates(ctx, root, HashCRC32, WithExclude("big"), WithMaxDepth(1))
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, []string{"a.txt", "b.txt"}, entryNames(root, groups[0]))
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		_, err := FindDuplicates(ctx, root, HashAlgorithm(-1))
+		assert.ErrorIs(t, err, ErrUnknownHash)
+	})
+}
+
+func TestHashEntriesVanished(t *testing.T) {
+	root := setupHashTest(t)
+
+	entries, err := collectFiles(context.Background(), root, newWalkOptions(WithInclude("*.txt")))
+	require.NoError(t, err)
+	SortEntries(entries, SortName, false)
+	require.NoError(t, os.Remove(entries[1].Path))
+
+	hashed, sums, err := hashEntries(context.Background(), entries, HashSHA256, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{entries[0], entries[2]}, hashed)
+	assert.Len(t, sums, 2)
+}
@@ -0,0 +1,74 @@
+package files
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// FindLargeFiles walks the tree under root and returns up to limit largest
+// regular files sorted by size in descending order. A limit of 0 or less
+// returns all matching files.
+//
+// Supported options: WithInclude, WithExclude, WithRegexp, WithMinSize,
+// WithMinAge, WithMaxAge, WithMaxDepth, WithSkipHidden and WithConcurrency.
+// Symbolic links are not followed.
+//
+// Example usage:
+//
+//	entries, err := FindLargeFiles(ctx, "/var/log", 20,
+//	    WithInclude("*.log", "*.gz"),
+//	    WithExclude("archive"),
+//	    WithMinSize(100<<20),
+//	    WithConcurrency(4),
+//	)
+func FindLargeFiles(ctx context.Context, root string, limit int, opts ...WalkOption) ([]Entry, error) {
+	var (
+		mu      sync.Mutex
+		largest entryHeap
+	)
+
+	err := walkFiles(ctx, root, newWalkOptions(opts...), func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if limit <= 0 || largest.Len() < limit {
+			heap.Push(&largest, entry)
+
+			return
+		}
+
+		if entry.Size > largest[0].Size {
+			largest[0] = entry
+			heap.Fix(&largest, 0)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry(largest)
+	SortEntries(entries, SortSize, false)
+
+	return entries, nil
+}
+
+// entryHeap is a min-heap of entries ordered by size.
+type entryHeap []Entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h entryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap) Push(x any) {
+	*h = append(*h, x.(Entry)) //nolint:forcetypeassert // only entries are pushed
+}
+
+func (h *entryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+
+	return item
+}
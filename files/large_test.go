@@ -0,0 +1,134 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLargeFilesTest(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	sizes := map[string]int{
+		"a.log":            100,
+		"b.log":            300,
+		"c.txt":            200,
+		"sub/d.log":        500,
+		"sub/e.txt":        50,
+		"sub/deep/f.log":   400,
+		"skip/g.log":       1000,
+		"sub/skip/h.log":   900,
+		"sub/deep/tie.log": 400,
+	}
+
+	for name, size := range sizes {
+		full := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(strings.Repeat("x", size)), 0o644))
+	}
+
+	return root
+}
+
+func entryNames(root string, entries []Entry) []string {
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		rel, _ := filepath.Rel(root, entry.Path)
+		names = append(names, filepath.ToSlash(rel))
+	}
+
+	return names
+}
+
+func TestFindLargeFiles(t *testing.T) {
+	root := setupLargeFilesTest(t)
+	ctx := context.Background()
+
+	t.Run("top n", func(t *testing.T) {
+		entries, err := FindLargeFiles(ctx, root, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"skip/g.log", "sub/skip/h.log", "sub/d.log"}, entryNames(root, entries))
+		assert.Equal(t, int64(1000), entries[0].Size)
+		assert.False(t, entries[0].ModTime.IsZero())
+	})
+
+	t.Run("all files", func(t *testing.T) {
+		entries, err := FindLargeFiles(ctx, root, 0)
+		require.NoError(t, err)
+		assert.Len(t, entries, 9)
+	})
+
+	t.Run("equal sizes sorted by path", func(t *testing.T) {
+		entries, err := FindLargeFiles(ctx, root, 2, WithInclude("sub/deep/*"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sub/deep/f.log", "sub/deep/tie.log"}, entryNames(root, entries))
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		entries, err := FindLargeFiles(ctx, root, 0, WithInclude("*.log"), WithExclude("skip", "tie.log"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sub/d.log", "sub/deep/f.log", "b.log", "a.log"}, entryNames(root, entries))
+	})
+
+	t.Run("regexp", func(t *testing.T) {
+		entries, err := FindLargeFiles(ctx, root, 0, WithRegexp(regexp.MustCompile(`\.txt$`)))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c.txt", "sub/e.txt"}, entryNames(root, entries))
+	})
+
+	t.Run("min size", func(t *testing.T) {
+		entries, err := FindLargeFiles(ctx, root, 0, WithMinSize(500))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"skip/g.log", "sub/skip/h.log", "sub/d.log"}, entryNames(root, entries))
+	})
+
+	t.Run("age", func(t *testing.T) {
+		old := time.Now().Add(-48 * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(root, "a.log"), old, old))
+
+		entries, err := FindLargeFiles(ctx, root, 0, WithMinAge(24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.log"}, entryNames(root, entries))
+
+		entries, err = FindLargeFiles(ctx, root, 0, WithMaxAge(24*time.Hour))
+		require.NoError(t, err)
+		assert.Len(t, entries, 8)
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		want, err := FindLargeFiles(ctx, root, 5)
+		require.NoError(t, err)
+
+		got, err := FindLargeFiles(ctx, root, 5, WithConcurrency(8))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := FindLargeFiles(canceled, root, 5, WithConcurrency(4))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		_, err := FindLargeFiles(ctx, filepath.Join(root, "a.log"), 5)
+		assert.ErrorIs(t, err, ErrNotDir)
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		_, err := FindLargeFiles(ctx, filepath.Join(root, "missing"), 5)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
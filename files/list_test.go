@@ -0,0 +1,126 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupListTest(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+
+	files := []struct {
+		name string
+		size int
+	}{
+		{"a.txt", 30},
+		{"b.log", 10},
+		{".hidden", 5},
+		{"sub/c.txt", 20},
+		{"sub/deep/d.txt", 40},
+		{".git/config", 1},
+	}
+
+	for i, file := range files {
+		full := filepath.Join(root, file.name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(strings.Repeat("x", file.size)), 0o644))
+
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(full, mtime, mtime))
+	}
+
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(root, "link")))
+
+	return root
+}
+
+func TestList(t *testing.T) {
+	root := setupListTest(t)
+	ctx := context.Background()
+
+	t.Run("all entries by name", func(t *testing.T) {
+		entries, err := List(ctx, root)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			".git", ".git/config", ".hidden", "a.txt", "b.log", "link",
+			"sub", "sub/c.txt", "sub/deep", "sub/deep/d.txt",
+		}, entryNames(root, entries))
+
+		byName := make(map[string]Entry)
+		for _, entry := range entries {
+			rel, _ := filepath.Rel(root, entry.Path)
+			byName[filepath.ToSlash(rel)] = entry
+		}
+
+		assert.True(t, byName["sub"].IsDir())
+		assert.False(t, byName["a.txt"].IsDir())
+		assert.Equal(t, int64(30), byName["a.txt"].Size)
+		assert.Equal(t, os.FileMode(0o644), byName["a.txt"].Mode.Perm())
+		assert.Equal(t, os.ModeSymlink, byName["link"].Mode.Type())
+	})
+
+	t.Run("max depth and hidden", func(t *testing.T) {
+		entries, err := List(ctx, root, WithMaxDepth(1), WithSkipHidden())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "b.log", "link", "sub"}, entryNames(root, entries))
+
+		entries, err = List(ctx, root, WithMaxDepth(2), WithSkipHidden())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "b.log", "link", "sub", "sub/c.txt", "sub/deep"}, entryNames(root, entries))
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		entries, err := List(ctx, root, WithInclude("*.txt"), WithMinSize(25))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "sub/deep/d.txt"}, entryNames(root, entries))
+
+		entries, err = List(ctx, root, WithRegexp(regexp.MustCompile(`^sub/`)), WithExclude("deep"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sub/c.txt"}, entryNames(root, entries))
+	})
+
+	t.Run("sort by size", func(t *testing.T) {
+		entries, err := List(ctx, root, WithInclude("*.txt", "*.log"))
+		require.NoError(t, err)
+
+		SortEntries(entries, SortSize, false)
+		assert.Equal(t, []string{"sub/deep/d.txt", "a.txt", "sub/c.txt", "b.log"}, entryNames(root, entries))
+	})
+
+	t.Run("sort by time reversed", func(t *testing.T) {
+		entries, err := List(ctx, root, WithInclude("*.txt", "*.log"))
+		require.NoError(t, err)
+
+		SortEntries(entries, SortTime, true)
+		assert.Equal(t, []string{"a.txt", "b.log", "sub/c.txt", "sub/deep/d.txt"}, entryNames(root, entries))
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		want, err := List(ctx, root)
+		require.NoError(t, err)
+
+		got, err := List(ctx, root, WithConcurrency(4))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := List(ctx, filepath.Join(root, "missing"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = List(ctx, filepath.Join(root, "a.txt"))
+		assert.ErrorIs(t, err, ErrNotDir)
+	})
+}
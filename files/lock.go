@@ -0,0 +1,238 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLocked is returned by TryLock when the file is locked by someone else.
+	ErrLocked = errors.New("file is locked")
+
+	// ErrAlreadyRunning is returned by CreatePIDFile when another live process
+	// owns the PID file.
+	ErrAlreadyRunning = errors.New("process is already running")
+)
+
+// lockRetryInterval is how often Lock retries a lock held by someone else.
+// flock(2) cannot be interrupted, so a blocking call would outlive the context.
+const lockRetryInterval = 50 * time.Millisecond
+
+// pidLockSuffix is appended to the PID file name to get its lock file name.
+const pidLockSuffix = ".lock"
+
+// LockMode is the kind of advisory lock taken on a file.
+type LockMode int
+
+const (
+	// LockExclusive allows a single holder.
+	LockExclusive LockMode = iota
+
+	// LockShared allows any number of shared holders but no exclusive one.
+	LockShared
+)
+
+// FileLock is an advisory flock(2) lock on a file. Locks are held by the open
+// file, so they are released when the process exits and are not shared
+// between two FileLock values even inside the same process. On platforms
+// without flock(2), such as Windows, locking fails with an error wrapping
+// errors.ErrUnsupported.
+type FileLock struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// Lock locks the file at path, creating it if it does not exist, and waits
+// until the lock is acquired or ctx is done.
+//
+// Example usage:
+//
+//	lock, err := Lock(ctx, "/var/lib/bot/data.lock", LockExclusive)
+//	if err != nil {
+//	    return err
+//	}
+//	defer lock.Unlock()
+func Lock(ctx context.Context, path string, mode LockMode) (*FileLock, error) {
+	lock, err := TryLock(path, mode)
+	if !errors.Is(err, ErrLocked) {
+		return lock, err
+	}
+
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		lock, err = TryLock(path, mode)
+		if !errors.Is(err, ErrLocked) {
+			return lock, err
+		}
+	}
+}
+
+// TryLock locks the file at path, creating it if it does not exist. It
+// returns ErrLocked at once if the lock is held by someone else.
+func TryLock(path string, mode LockMode) (*FileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, DefaultFilePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file, mode); err != nil {
+		file.Close()
+
+		return nil, &fs.PathError{Op: "flock", Path: path, Err: err}
+	}
+
+	return &FileLock{file: file, path: path}, nil
+}
+
+// Path returns the path of the locked file.
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock releases the lock. The lock file is left in place: removing it
+// would let a new holder lock a fresh file while the old one is still held.
+// Unlock is safe to call more than once.
+func (l *FileLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	l.file = nil
+
+	return err
+}
+
+// PIDFile is a PID file owned by the current process.
+type PIDFile struct {
+	mu   sync.Mutex
+	path string
+	lock *FileLock
+}
+
+// CreatePIDFile makes the current process the single owner of the PID file at
+// path.
+//
+// Behavior details:
+//   - An exclusive lock is taken on path + ".lock" and held until Close, so a
+//     second instance fails even if it starts while the first one is writing
+//   - An existing PID file is stale if its process is no longer alive and is
+//     then overwritten. A live process fails with ErrAlreadyRunning, which
+//     also covers owners that do not take the lock
+//   - The PID is written atomically, readers never see a partial file
+//
+// Example usage:
+//
+//	pid, err := CreatePIDFile("/run/bot.pid")
+//	if errors.Is(err, ErrAlreadyRunning) {
+//	    log.Fatal("bot is already running")
+//	}
+//	defer pid.Close()
+//
+// Warning:
+//   - PIDs are reused by the system, a stale PID file may point at an
+//     unrelated live process, and the lock is the only reliable check.
+func CreatePIDFile(path string) (*PIDFile, error) {
+	lock, err := TryLock(path+pidLockSuffix, LockExclusive)
+	if err != nil {
+		if errors.Is(err, ErrLocked) {
+			return nil, runningError(path)
+		}
+
+		return nil, err
+	}
+
+	if pid, err := ReadPIDFile(path); err == nil && pid != os.Getpid() && processAlive(pid) {
+		lock.Unlock()
+
+		return nil, runningError(path)
+	}
+
+	if err := WriteFileAtomicString(path, strconv.Itoa(os.Getpid())+"\n", 0o644); err != nil {
+		lock.Unlock()
+
+		return nil, err
+	}
+
+	return &PIDFile{path: path, lock: lock}, nil
+}
+
+// ReadPIDFile returns the PID stored in the PID file at path.
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, &fs.PathError{Op: "read pid", Path: path, Err: fs.ErrInvalid}
+	}
+
+	return pid, nil
+}
+
+// Path returns the path of the PID file.
+func (p *PIDFile) Path() string {
+	return p.path
+}
+
+// Close removes the PID file and releases its lock. The file is kept if it
+// no longer holds the PID of the current process. Close is safe to call more
+// than once.
+func (p *PIDFile) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lock == nil {
+		return nil
+	}
+
+	var err error
+
+	if pid, readErr := ReadPIDFile(p.path); readErr == nil && pid == os.Getpid() {
+		if err = os.Remove(p.path); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+
+	if unlockErr := p.lock.Unlock(); err == nil {
+		err = unlockErr
+	}
+
+	p.lock = nil
+
+	return err
+}
+
+// runningError describes the live owner of the PID file at path.
+func runningError(path string) error {
+	if pid, err := ReadPIDFile(path); err == nil {
+		return fmt.Errorf("%s: %w with pid %d", path, ErrAlreadyRunning, pid)
+	}
+
+	return fmt.Errorf("%s: %w", path, ErrAlreadyRunning)
+}
@@ -0,0 +1,53 @@
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd
+
+package files
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes the lock on file without waiting, or returns ErrLocked.
+func lockFile(file *os.File, mode LockMode) error {
+	how := syscall.LOCK_EX
+	if mode == LockShared {
+		how = syscall.LOCK_SH
+	}
+
+	err := flock(file, how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	}
+
+	return err
+}
+
+// unlockFile releases the lock on file.
+func unlockFile(file *os.File) error {
+	return flock(file, syscall.LOCK_UN)
+}
+
+// flock calls flock(2) on file, retrying when interrupted by a signal.
+func flock(file *os.File, how int) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var lockErr error
+
+	err = conn.Control(func(fd uintptr) {
+		for {
+			lockErr = syscall.Flock(int(fd), how) //nolint:gosec // fd fits in int
+			if !errors.Is(lockErr, syscall.EINTR) {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return lockErr
+}
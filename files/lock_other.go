@@ -0,0 +1,18 @@
+//go:build !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd
+
+package files
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile reports that flock(2) is not available on this platform.
+func lockFile(_ *os.File, _ LockMode) error {
+	return errors.ErrUnsupported
+}
+
+// unlockFile reports that flock(2) is not available on this platform.
+func unlockFile(_ *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,155 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.lock")
+
+	t.Run("exclusive", func(t *testing.T) {
+		lock, err := TryLock(path, LockExclusive)
+		require.NoError(t, err)
+		assert.Equal(t, path, lock.Path())
+
+		_, err = TryLock(path, LockExclusive)
+		assert.ErrorIs(t, err, ErrLocked)
+
+		_, err = TryLock(path, LockShared)
+		assert.ErrorIs(t, err, ErrLocked)
+
+		require.NoError(t, lock.Unlock())
+		require.NoError(t, lock.Unlock())
+
+		lock, err = TryLock(path, LockExclusive)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		first, err := TryLock(path, LockShared)
+		require.NoError(t, err)
+
+		second, err := TryLock(path, LockShared)
+		require.NoError(t, err)
+
+		_, err = TryLock(path, LockExclusive)
+		assert.ErrorIs(t, err, ErrLocked)
+
+		require.NoError(t, first.Unlock())
+		require.NoError(t, second.Unlock())
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := TryLock(filepath.Join(path, "nested", "data.lock"), LockExclusive)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrLocked)
+	})
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.lock")
+
+	held, err := TryLock(path, LockExclusive)
+	require.NoError(t, err)
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*lockRetryInterval)
+		defer cancel()
+
+		_, err := Lock(ctx, path, LockShared)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("waits for release", func(t *testing.T) {
+		go func() {
+			time.Sleep(2 * lockRetryInterval)
+			_ = held.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		lock, err := Lock(ctx, path, LockExclusive)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+	})
+}
+
+func TestCreatePIDFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.pid")
+
+	t.Run("single instance", func(t *testing.T) {
+		pidFile, err := CreatePIDFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, path, pidFile.Path())
+
+		pid, err := ReadPIDFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.Getpid(), pid)
+
+		_, err = CreatePIDFile(path)
+		require.ErrorIs(t, err, ErrAlreadyRunning)
+		assert.Contains(t, err.Error(), strconv.Itoa(os.Getpid()))
+
+		require.NoError(t, pidFile.Close())
+		require.NoError(t, pidFile.Close())
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		// Above the maximum PID of the system, so never alive.
+		require.NoError(t, os.WriteFile(path, []byte("2147483600\n"), 0o644))
+
+		pidFile, err := CreatePIDFile(path)
+		require.NoError(t, err)
+
+		pid, err := ReadPIDFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.Getpid(), pid)
+
+		require.NoError(t, pidFile.Close())
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("not a pid"), 0o644))
+
+		_, err := ReadPIDFile(path)
+		assert.ErrorIs(t, err, os.ErrInvalid)
+
+		pidFile, err := CreatePIDFile(path)
+		require.NoError(t, err)
+		require.NoError(t, pidFile.Close())
+	})
+
+	t.Run("live owner without lock", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("1\n"), 0o644))
+
+		_, err := CreatePIDFile(path)
+		assert.ErrorIs(t, err, ErrAlreadyRunning)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "1\n", string(data))
+	})
+
+	t.Run("replaced file is kept", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+
+		pidFile, err := CreatePIDFile(path)
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(path, []byte("1\n"), 0o644))
+		require.NoError(t, pidFile.Close())
+		assert.FileExists(t, path)
+	})
+}
@@ -0,0 +1,404 @@
+package files
+
+import (
+	"os"
+	"regexp"
+	"time"
+)
+
+// pathFilter selects files by their slash separated path relative to a root.
+type pathFilter struct {
+	include []string
+	exclude []string
+	pattern *regexp.Regexp
+}
+
+// excluded reports whether the relative path matches an exclude pattern.
+func (f *pathFilter) excluded(rel string) bool {
+	return matchAny(f.exclude, rel)
+}
+
+// selected reports whether the relative path matches the include patterns
+// and the regular expression.
+func (f *pathFilter) selected(rel string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, rel) {
+		return false
+	}
+
+	return f.pattern == nil || f.pattern.MatchString(rel)
+}
+
+// WalkOption configures the functions walking a tree: List, FindLargeFiles,
+// HashTree, WriteManifest, FindDuplicates, CreateArchive and Cleanup.
+type WalkOption func(o *walkOptions)
+
+// walkOptions holds the settings of a tree walk.
+type walkOptions struct {
+	pathFilter
+
+	minSize     int64
+	minAge      time.Duration
+	maxAge      time.Duration
+	maxDepth    int
+	skipHidden  bool
+	concurrency int
+}
+
+func newWalkOptions(opts ...WalkOption) *walkOptions {
+	o := &walkOptions{
+		concurrency: 1,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithInclude keeps only files matching at least one of the glob patterns.
+// Patterns without a "/" are matched against the file name, the others
+// against the slash separated path relative to the walked root.
+func WithInclude(patterns ...string) WalkOption {
+	return func(o *walkOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExclude skips files and directories matching any of the glob patterns.
+// Excluded directories are not descended into. Patterns are matched the same
+// way as in WithInclude.
+func WithExclude(patterns ...string) WalkOption {
+	return func(o *walkOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithRegexp keeps only files whose slash separated path relative to the
+// walked root matches pattern.
+func WithRegexp(pattern *regexp.Regexp) WalkOption {
+	return func(o *walkOptions) {
+		o.pattern = pattern
+	}
+}
+
+// WithMinSize keeps only files of at least size bytes.
+func WithMinSize(size int64) WalkOption {
+	return func(o *walkOptions) {
+		o.minSize = size
+	}
+}
+
+// WithMinAge keeps only files modified at least age ago.
+func WithMinAge(age time.Duration) WalkOption {
+	return func(o *walkOptions) {
+		o.minAge = age
+	}
+}
+
+// WithMaxAge keeps only files modified within the last age.
+func WithMaxAge(age time.Duration) WalkOption {
+	return func(o *walkOptions) {
+		o.maxAge = age
+	}
+}
+
+// WithMaxDepth limits how deep the tree is walked: 1 means only the items of
+// the root itself. Values below 1 mean no limit.
+func WithMaxDepth(depth int) WalkOption {
+	return func(o *walkOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// WithSkipHidden skips files and directories whose name starts with a dot.
+// Hidden directories are not descended into.
+func WithSkipHidden() WalkOption {
+	return func(o *walkOptions) {
+		o.skipHidden = true
+	}
+}
+
+// WithConcurrency sets how many directories are read in parallel, and how
+// many files are hashed in parallel by HashTree and FindDuplicates.
+// Values below 1 mean 1.
+func WithConcurrency(n int) WalkOption {
+	return func(o *walkOptions) {
+		o.concurrency = max(n, 1)
+	}
+}
+
+// ExtractOption configures ExtractArchive and ExtractArchiveFile.
+type ExtractOption func(o *extractOptions)
+
+// extractOptions holds the settings of an extraction.
+type extractOptions struct {
+	pathFilter
+}
+
+func newExtractOptions(opts ...ExtractOption) *extractOptions {
+	o := &extractOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithExtractInclude extracts only files matching at least one of the glob
+// patterns. Patterns are matched the same way as in WithInclude, against the
+// path inside the archive.
+func WithExtractInclude(patterns ...string) ExtractOption {
+	return func(o *extractOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExtractExclude skips files and directories matching any of the glob
+// patterns, including everything below an excluded directory.
+func WithExtractExclude(patterns ...string) ExtractOption {
+	return func(o *extractOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// CopyOption configures Copy and CopyDir.
+type CopyOption func(o *copyOptions)
+
+// copyOptions holds the settings of a copy.
+type copyOptions struct {
+	preserveMode   bool
+	preserveTimes  bool
+	preserveOwner  bool
+	followSymlinks bool
+	fileMode       os.FileMode
+	progress       ProgressFunc
+}
+
+func newCopyOptions(opts ...CopyOption) *copyOptions {
+	o := &copyOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithPreserveMode copies the permission bits of the source, including the
+// setuid, setgid and sticky bits, regardless of the umask.
+func WithPreserveMode() CopyOption {
+	return func(o *copyOptions) {
+		o.preserveMode = true
+	}
+}
+
+// WithPreserveTimes copies the access and modification times of the source.
+func WithPreserveTimes() CopyOption {
+	return func(o *copyOptions) {
+		o.preserveTimes = true
+	}
+}
+
+// WithPreserveOwner copies the owner and group of the source. Only root may
+// give files away, so ownership is silently kept when the change is not
+// permitted, the same way `cp -p` does it.
+func WithPreserveOwner() CopyOption {
+	return func(o *copyOptions) {
+		o.preserveOwner = true
+	}
+}
+
+// WithFollowSymlinks copies the files symbolic links point to instead of
+// the links themselves.
+func WithFollowSymlinks() CopyOption {
+	return func(o *copyOptions) {
+		o.followSymlinks = true
+	}
+}
+
+// WithFileMode sets the permission (before umask) of newly created files.
+// By default the permission of the source file is used.
+func WithFileMode(mode os.FileMode) CopyOption {
+	return func(o *copyOptions) {
+		o.fileMode = mode
+	}
+}
+
+// WithProgress sets a callback reporting the progress of copying files.
+func WithProgress(progress ProgressFunc) CopyOption {
+	return func(o *copyOptions) {
+		o.progress = progress
+	}
+}
+
+// SyncOption configures SyncDir.
+type SyncOption func(o *syncOptions)
+
+// syncOptions holds the settings of a sync.
+type syncOptions struct {
+	pathFilter
+
+	checksum bool
+	hash     HashAlgorithm
+	delete   bool
+	dryRun   bool
+}
+
+func newSyncOptions(opts ...SyncOption) *syncOptions {
+	o := &syncOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithSyncInclude syncs only files matching at least one of the glob
+// patterns. Patterns are matched the same way as in WithInclude.
+func WithSyncInclude(patterns ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithSyncExclude skips files and directories matching any of the glob
+// patterns, on both sides. Patterns are matched the same way as in
+// WithInclude.
+func WithSyncExclude(patterns ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithChecksum compares files by hash instead of modification time. Files
+// of different sizes are never hashed.
+func WithChecksum(algo HashAlgorithm) SyncOption {
+	return func(o *syncOptions) {
+		o.checksum = true
+		o.hash = algo
+	}
+}
+
+// WithDelete removes the entries of the destination missing in the source.
+func WithDelete() SyncOption {
+	return func(o *syncOptions) {
+		o.delete = true
+	}
+}
+
+// WithDryRun reports what would be done without changing anything.
+func WithDryRun() SyncOption {
+	return func(o *syncOptions) {
+		o.dryRun = true
+	}
+}
+
+// WatchOption configures NewWatcher.
+type WatchOption func(o *watchOptions)
+
+// watchOptions holds the settings of a watcher.
+type watchOptions struct {
+	pathFilter
+
+	debounce     time.Duration
+	pollInterval time.Duration
+}
+
+func newWatchOptions(opts ...WatchOption) *watchOptions {
+	o := &watchOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithWatchInclude reports only events of files matching at least one of the
+// glob patterns. Patterns are matched the same way as in WithInclude.
+func WithWatchInclude(patterns ...string) WatchOption {
+	return func(o *watchOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithWatchExclude ignores files and directories matching any of the glob
+// patterns. Excluded directories are not watched.
+func WithWatchExclude(patterns ...string) WatchOption {
+	return func(o *watchOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithDebounce merges the events of a path until it has been quiet for d,
+// so a burst of writes is reported once.
+func WithDebounce(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.debounce = d
+	}
+}
+
+// WithPollInterval makes the watcher poll the tree every d instead of using
+// kernel notifications, e.g. on network filesystems not supporting them.
+func WithPollInterval(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.pollInterval = d
+	}
+}
+
+// RotateOption configures NewRotatingWriter.
+type RotateOption func(o *rotateOptions)
+
+// rotateOptions holds the settings of a RotatingWriter.
+type rotateOptions struct {
+	maxSize    int64
+	maxBackups int
+	compress   bool
+	fileMode   os.FileMode
+}
+
+func newRotateOptions(opts ...RotateOption) *rotateOptions {
+	o := &rotateOptions{
+		fileMode: DefaultFilePerm,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithMaxSize rotates files once they would grow over size bytes.
+// Values below 1 disable size based rotation.
+func WithMaxSize(size int64) RotateOption {
+	return func(o *rotateOptions) {
+		o.maxSize = size
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files, the oldest are removed.
+// Values below 1 keep all of them.
+func WithMaxBackups(n int) RotateOption {
+	return func(o *rotateOptions) {
+		o.maxBackups = n
+	}
+}
+
+// WithCompress gzip-compresses rotated files.
+func WithCompress() RotateOption {
+	return func(o *rotateOptions) {
+		o.compress = true
+	}
+}
+
+// WithRotateFileMode sets the permission (before umask) of the log files.
+// The default is DefaultFilePerm.
+func WithRotateFileMode(mode os.FileMode) RotateOption {
+	return func(o *rotateOptions) {
+		o.fileMode = mode
+	}
+}
@@ -0,0 +1,263 @@
+package files
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinks limits how many symbolic links are followed while resolving
+// a path, the same limit Linux applies.
+const maxSymlinks = 40
+
+// BaseDir is a directory relative paths are resolved against by ResolvePath.
+type BaseDir int
+
+const (
+	// BaseExecutable is the directory of the running executable, with
+	// symbolic links resolved, see os.Executable.
+	BaseExecutable BaseDir = iota
+
+	// BaseWorking is the current working directory.
+	BaseWorking
+
+	// BaseConfig is the user configuration directory, $XDG_CONFIG_HOME or
+	// ~/.config.
+	BaseConfig
+
+	// BaseData is the user data directory, $XDG_DATA_HOME or ~/.local/share.
+	BaseData
+)
+
+// String returns the name of the base directory.
+func (b BaseDir) String() string {
+	switch b {
+	case BaseExecutable:
+		return "executable"
+	case BaseWorking:
+		return "working"
+	case BaseConfig:
+		return "config"
+	case BaseData:
+		return "data"
+	default:
+		return fmt.Sprintf("BaseDir(%d)", int(b))
+	}
+}
+
+// Path returns the absolute path of the base directory. The XDG variables
+// are only used when they hold absolute paths, as the specification asks.
+func (b BaseDir) Path() (string, error) {
+	switch b {
+	case BaseExecutable:
+		exe, err := os.Executable()
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Dir(exe), nil
+	case BaseWorking:
+		return os.Getwd()
+	case BaseConfig:
+		return xdgDir("XDG_CONFIG_HOME", ".config")
+	case BaseData:
+		return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	default:
+		return "", fmt.Errorf("unknown base directory: %s", b)
+	}
+}
+
+// xdgDir returns the directory of the XDG variable env or its default
+// relative to the home directory.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Clean(dir), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, fallback), nil
+}
+
+// ExpandPath expands a leading "~" or "~user" to the home directory and
+// replaces $VAR and ${VAR} with the values of environment variables.
+// Undefined variables are replaced with an empty string.
+//
+// Example usage:
+//
+//	path, err := ExpandPath("~/backups/$APP_ENV")
+//	// Returns "/home/bot/backups/production"
+func ExpandPath(path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+
+	return os.ExpandEnv(path), nil
+}
+
+// expandHome expands a leading "~" or "~user" to the home directory.
+func expandHome(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		name, rest, _ := strings.Cut(path[1:], string(filepath.Separator))
+
+		var (
+			home string
+			err  error
+		)
+
+		if name == "" {
+			home, err = os.UserHomeDir()
+		} else {
+			var u *user.User
+			if u, err = user.Lookup(name); err == nil {
+				home = u.HomeDir
+			}
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		path = filepath.Join(home, rest)
+	}
+
+	return path, nil
+}
+
+// ResolvePath expands path with ExpandPath and makes it absolute. Relative
+// paths are resolved against base.
+//
+// Example usage:
+//
+//	// Config file next to the binary unless an absolute path is given.
+//	path, err := ResolvePath(cfg.File, BaseExecutable)
+//
+//	// State kept in ~/.local/share/bot.
+//	path, err := ResolvePath("bot/state.json", BaseData)
+func ResolvePath(path string, base BaseDir) (string, error) {
+	path, err := ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	dir, err := base.Path()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, path), nil
+}
+
+// SecureJoin joins name to root and guarantees the result stays inside root.
+// Symbolic links are resolved, so neither ".." elements nor links can lead
+// out of root.
+//
+// Parameters:
+//   - root: trusted directory, it does not have to exist
+//   - name: untrusted relative path, e.g. taken from a request or an archive
+//
+// Returns:
+//   - string: the joined path with symbolic links resolved
+//   - error: ErrUnsafePath if name is absolute or leads out of root,
+//     ErrSymlinkLoop for too many links, or an error reading a link
+//
+// Behavior details:
+//   - ".." elements staying inside root are allowed, "a/../b" is root/b
+//   - Links inside root pointing inside root are fine, the result holds
+//     the resolved path
+//   - Dangling links are followed as well, writing through them would
+//     create their target
+//
+// Example usage:
+//
+//	path, err := SecureJoin("/srv/static", r.URL.Query().Get("file"))
+//	if errors.Is(err, ErrUnsafePath) {
+//	    http.Error(w, "forbidden", http.StatusForbidden)
+//	}
+//
+// Warning:
+//   - The check is done when called, a link changed afterwards by someone
+//     with write access to root may still lead out of it.
+func SecureJoin(root, name string) (string, error) {
+	if name != "" && !filepath.IsLocal(name) {
+		return "", &fs.PathError{Op: "join", Path: name, Err: ErrUnsafePath}
+	}
+
+	resolvedRoot, err := resolvePath(root)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := resolvePath(filepath.Join(resolvedRoot, name))
+	if err != nil {
+		return "", err
+	}
+
+	if !pathWithin(resolved, resolvedRoot) {
+		return "", &fs.PathError{Op: "join", Path: name, Err: ErrUnsafePath}
+	}
+
+	return resolved, nil
+}
+
+// resolvePath returns the absolute path of name with symbolic links resolved
+// as far as it exists, dangling links included.
+func resolvePath(name string) (string, error) {
+	return resolveLinks(name, 0)
+}
+
+func resolveLinks(name string, links int) (string, error) {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(name)
+	if err == nil || !os.IsNotExist(err) {
+		return resolved, err
+	}
+
+	dir := filepath.Dir(name)
+	if dir == name {
+		return name, nil
+	}
+
+	parent, err := resolveLinks(dir, links)
+	if err != nil {
+		return "", err
+	}
+
+	full := filepath.Join(parent, filepath.Base(name))
+
+	target, err := os.Readlink(full)
+	if err != nil {
+		// Missing, the path is resolved as far as it goes.
+		return full, nil //nolint:nilerr // not a link
+	}
+
+	if links >= maxSymlinks {
+		return "", &fs.PathError{Op: "resolve", Path: name, Err: ErrSymlinkLoop}
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(parent, target)
+	}
+
+	return resolveLinks(target, links+1)
+}
+
+// pathWithin reports whether the clean absolute path is dir or inside it.
+func pathWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,140 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	t.Setenv("GOLIBS_APP", "bot")
+
+	tests := map[string]string{
+		"~":                  home,
+		"~/":                 home,
+		"~/data":             filepath.Join(home, "data"),
+		"~/$GOLIBS_APP/data": filepath.Join(home, "bot", "data"),
+		"/var/${GOLIBS_APP}": "/var/bot",
+		"$GOLIBS_UNSET/data": "/data",
+		"relative/~/path":    "relative/~/path",
+	}
+
+	for path, expected := range tests {
+		t.Run(path, func(t *testing.T) {
+			result, err := ExpandPath(path)
+			require.NoError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := ExpandPath("~golibs-no-such-user/data")
+		assert.Error(t, err)
+	})
+}
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", "relative/is/ignored")
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	tests := []struct {
+		base     BaseDir
+		expected string
+	}{
+		{BaseExecutable, filepath.Join(filepath.Dir(exe), "bot", "state.json")},
+		{BaseWorking, filepath.Join(wd, "bot", "state.json")},
+		{BaseConfig, filepath.Join(dir, "config", "bot", "state.json")},
+		{BaseData, filepath.Join(home, ".local", "share", "bot", "state.json")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base.String(), func(t *testing.T) {
+			result, err := ResolvePath("bot/./state.json", tt.base)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+
+			result, err = ResolvePath("/etc//bot/../bot.conf", tt.base)
+			require.NoError(t, err)
+			assert.Equal(t, "/etc/bot.conf", result)
+		})
+	}
+
+	t.Run("unknown base", func(t *testing.T) {
+		_, err := ResolvePath("bot", BaseDir(42))
+		assert.Error(t, err)
+	})
+}
+
+func TestSecureJoin(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.Symlink("sub", filepath.Join(root, "inside")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink("../../outside", filepath.Join(root, "sub", "relescape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(root, "dangling")))
+	require.NoError(t, os.Symlink("loop", filepath.Join(root, "loop")))
+
+	t.Run("inside", func(t *testing.T) {
+		tests := map[string]string{
+			"":                   root,
+			"file.txt":           filepath.Join(root, "file.txt"),
+			"sub/../file.txt":    filepath.Join(root, "file.txt"),
+			"inside/file.txt":    filepath.Join(root, "sub", "file.txt"),
+			"missing/a/file.txt": filepath.Join(root, "missing", "a", "file.txt"),
+		}
+
+		for name, expected := range tests {
+			result, err := SecureJoin(root, name)
+			require.NoError(t, err, name)
+			assert.Equal(t, expected, result, name)
+		}
+	})
+
+	t.Run("escaping", func(t *testing.T) {
+		for _, name := range []string{
+			"../file.txt",
+			"sub/../../file.txt",
+			"/etc/passwd",
+			"escape/file.txt",
+			"sub/relescape/file.txt",
+			"dangling",
+		} {
+			_, err := SecureJoin(root, name)
+			assert.ErrorIs(t, err, ErrUnsafePath, name)
+		}
+	})
+
+	t.Run("link loop", func(t *testing.T) {
+		_, err := SecureJoin(root, "loop")
+		assert.Error(t, err)
+	})
+
+	t.Run("root through link", func(t *testing.T) {
+		link := filepath.Join(base, "link")
+		require.NoError(t, os.Symlink(root, link))
+
+		result, err := SecureJoin(link, "inside")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "sub"), result)
+	})
+}
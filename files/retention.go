@@ -0,0 +1,181 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CleanupResult reports the files removed by Cleanup.
+type CleanupResult struct {
+	// Removed holds the removed files, oldest first. In dry-run mode it
+	// holds the files that would be removed.
+	Removed []Entry
+
+	// Freed is the total size of Removed in bytes.
+	Freed int64
+}
+
+// RetentionPolicy holds the retention rules of Cleanup. A file is removed if
+// it violates at least one of them, zero values disable a rule.
+type RetentionPolicy struct {
+	// OlderThan removes files modified more than this long ago.
+	OlderThan time.Duration
+
+	// KeepNewest removes all but this many most recently modified files.
+	KeepNewest int
+
+	// SizeBudget removes the oldest files until the total size of the
+	// remaining ones is at most this many bytes.
+	SizeBudget int64
+
+	// DryRun only reports what would be removed.
+	DryRun bool
+}
+
+// Cleanup applies retention rules to the regular files under root and
+// removes the files violating any of them.
+//
+// Parameters:
+//   - ctx: stops the cleanup when done
+//   - root: directory to clean up recursively
+//   - policy: retention rules
+//   - opts: filters selecting the files the rules apply to
+//
+// Returns:
+//   - *CleanupResult: removed files, also returned along with an error for
+//     the files removed before it occurred
+//   - error: ErrUnsafePath, walk or removal error
+//
+// Behavior details:
+//   - Without rules in policy nothing is removed
+//   - WithInclude, WithExclude, WithRegexp, WithMinSize, WithMinAge,
+//     WithMaxAge, WithMaxDepth and WithSkipHidden select the files the rules
+//     apply to; other files are neither removed nor counted. WithConcurrency
+//     sets how many directories are read in parallel
+//   - Directories are never removed, even when left empty
+//   - Refuses to operate on "/" and on the home directory of the current
+//     user with ErrUnsafePath, symbolic links are resolved before the check
+//
+// Example usage:
+//
+//	// Keep two weeks of backups but at most 50 GiB of them.
+//	res, err := Cleanup(ctx, "/var/backups/db",
+//	    RetentionPolicy{OlderThan: 14 * 24 * time.Hour, SizeBudget: 50 << 30},
+//	    WithInclude("*.dump.gz"),
+//	)
+//
+// Warning:
+//   - This is a destructive operation, run it with DryRun set first.
+func Cleanup(ctx context.Context, root string, policy RetentionPolicy, opts ...WalkOption) (*CleanupResult, error) {
+	o := newWalkOptions(opts...)
+
+	if err := checkSafePath(root); err != nil {
+		return nil, err
+	}
+
+	var (
+		mu      sync.Mutex
+		entries = make([]Entry, 0)
+	)
+
+	err := walkFiles(ctx, root, o, func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CleanupResult{Removed: make([]Entry, 0)}
+
+	expired := policy.expired(entries, time.Now())
+
+	// Remove the oldest files first, so an interrupted cleanup keeps the
+	// newest ones.
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !expired[i] {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		entry := entries[i]
+
+		if !policy.DryRun {
+			if err := os.Remove(entry.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return result, err
+			}
+		}
+
+		result.Removed = append(result.Removed, entry)
+		result.Freed += entry.Size
+	}
+
+	return result, nil
+}
+
+// expired sorts entries newest first and reports which of them violate the
+// retention rules.
+func (p RetentionPolicy) expired(entries []Entry, now time.Time) []bool {
+	SortEntries(entries, SortTime, false)
+
+	expired := make([]bool, len(entries))
+
+	var (
+		total      int64
+		overBudget bool
+	)
+
+	for i, entry := range entries {
+		expired[i] = (p.OlderThan > 0 && now.Sub(entry.ModTime) > p.OlderThan) ||
+			(p.KeepNewest > 0 && i >= p.KeepNewest)
+
+		// Files removed by the other rules do not count against the budget,
+		// once it is exceeded all older files go.
+		if p.SizeBudget > 0 && !expired[i] {
+			total += entry.Size
+			overBudget = overBudget || total > p.SizeBudget
+			expired[i] = overBudget
+		}
+	}
+
+	return expired
+}
+
+// checkSafePath refuses paths destructive operations must never touch.
+func checkSafePath(path string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return err
+	}
+
+	unsafe := []string{string(filepath.Separator)}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		if home, err := filepath.EvalSymlinks(home); err == nil {
+			unsafe = append(unsafe, home)
+		}
+	}
+
+	for _, dir := range unsafe {
+		if resolved == filepath.Clean(dir) {
+			return &fs.PathError{Op: "cleanup", Path: path, Err: ErrUnsafePath}
+		}
+	}
+
+	return nil
+}
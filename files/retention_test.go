@@ -0,0 +1,128 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRetentionTest creates backup-N.tar files aged N hours with N*10 bytes
+// and an unrelated notes.txt.
+func setupRetentionTest(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	now := time.Now()
+
+	for i := 1; i <= 5; i++ {
+		name := filepath.Join(root, "backup-"+strings.Repeat("x", i)+".tar")
+		require.NoError(t, os.WriteFile(name, []byte(strings.Repeat("x", i*10)), 0o644))
+
+		mtime := now.Add(-time.Duration(i) * time.Hour)
+		require.NoError(t, os.Chtimes(name, mtime, mtime))
+	}
+
+	notes := filepath.Join(root, "notes.txt")
+	require.NoError(t, os.WriteFile(notes, []byte("keep"), 0o644))
+
+	old := now.Add(-100 * time.Hour)
+	require.NoError(t, os.Chtimes(notes, old, old))
+
+	return root
+}
+
+func removedNames(root string, result *CleanupResult) []string {
+	return entryNames(root, result.Removed)
+}
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("older than", func(t *testing.T) {
+		root := setupRetentionTest(t)
+
+		result, err := Cleanup(ctx, root, RetentionPolicy{OlderThan: 150 * time.Minute}, WithInclude("*.tar"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"backup-xxxxx.tar", "backup-xxxx.tar", "backup-xxx.tar"}, removedNames(root, result))
+		assert.Equal(t, int64(120), result.Freed)
+		assert.NoFileExists(t, filepath.Join(root, "backup-xxx.tar"))
+		assert.FileExists(t, filepath.Join(root, "backup-xx.tar"))
+		assert.FileExists(t, filepath.Join(root, "notes.txt"))
+	})
+
+	t.Run("keep newest", func(t *testing.T) {
+		root := setupRetentionTest(t)
+
+		result, err := Cleanup(ctx, root, RetentionPolicy{KeepNewest: 2}, WithInclude("*.tar"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"backup-xxxxx.tar", "backup-xxxx.tar", "backup-xxx.tar"}, removedNames(root, result))
+	})
+
+	t.Run("size budget", func(t *testing.T) {
+		root := setupRetentionTest(t)
+
+		// 10 + 20 + 30 fit, the 40 byte file exceeds the budget, so does
+		// everything older.
+		result, err := Cleanup(ctx, root, RetentionPolicy{SizeBudget: 65}, WithInclude("*.tar"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"backup-xxxxx.tar", "backup-xxxx.tar"}, removedNames(root, result))
+		assert.Equal(t, int64(90), result.Freed)
+	})
+
+	t.Run("combined rules", func(t *testing.T) {
+		root := setupRetentionTest(t)
+
+		result, err := Cleanup(ctx, root, RetentionPolicy{KeepNewest: 4, SizeBudget: 25}, WithInclude("*.tar"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"backup-xxxxx.tar", "backup-xxxx.tar", "backup-xxx.tar", "backup-xx.tar"}, removedNames(root, result))
+	})
+
+	t.Run("no rules", func(t *testing.T) {
+		root := setupRetentionTest(t)
+
+		result, err := Cleanup(ctx, root, RetentionPolicy{})
+		require.NoError(t, err)
+		assert.Empty(t, result.Removed)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		root := setupRetentionTest(t)
+
+		result, err := Cleanup(ctx, root, RetentionPolicy{KeepNewest: 1, DryRun: true})
+		require.NoError(t, err)
+		assert.Len(t, result.Removed, 5)
+
+		entries, err := os.ReadDir(root)
+		require.NoError(t, err)
+		assert.Len(t, entries, 6)
+	})
+
+	t.Run("unsafe paths", func(t *testing.T) {
+		_, err := Cleanup(ctx, "/", RetentionPolicy{OlderThan: time.Hour, DryRun: true})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+
+		home, err := os.UserHomeDir()
+		require.NoError(t, err)
+
+		_, err = Cleanup(ctx, home+"/.", RetentionPolicy{OlderThan: time.Hour, DryRun: true})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+
+		link := filepath.Join(t.TempDir(), "root")
+		require.NoError(t, os.Symlink("/", link))
+
+		_, err = Cleanup(ctx, link, RetentionPolicy{OlderThan: time.Hour, DryRun: true})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		_, err := Cleanup(ctx, filepath.Join(t.TempDir(), "missing"), RetentionPolicy{OlderThan: time.Hour})
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,413 @@
+package files
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupLayout is the time layout of the suffix appended to files rotated
+// because of their size.
+const backupLayout = "20060102T150405,000"
+
+// compressSuffix is the extension of compressed backups.
+const compressSuffix = ".gz"
+
+// RotatingWriter is an io.WriteCloser writing to a file that is rotated by
+// size and by time. It is safe for concurrent use.
+//
+// The name of the current file is the layout formatted with the current
+// time, so "app.log" never changes while "app-2006-01-02.log" switches to a
+// new file every day. Files rotated because of their size get a timestamp
+// suffix, e.g. "app.log.20240102T150405,000". Both kinds of old files are
+// backups: they are compressed and pruned according to the options.
+//
+// Compression and pruning run in the background after every rotation;
+// their errors are returned by Close.
+type RotatingWriter struct {
+	dir    string
+	layout string
+	opts   *rotateOptions
+
+	now func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	name   string
+	size   int64
+	closed bool
+
+	// mill serializes the background compression and pruning.
+	mill sync.Mutex
+	wg   sync.WaitGroup
+
+	errMu sync.Mutex
+	err   error
+}
+
+// NewRotatingWriter opens the current file for appending, creating dir if
+// needed.
+//
+// Supported options: WithMaxSize, WithMaxBackups, WithCompress and
+// WithRotateFileMode (default DefaultFilePerm).
+//
+// Example usage:
+//
+//	w, err := NewRotatingWriter("/var/log/myapp", "myapp-2006-01-02.log",
+//	    WithMaxSize(100<<20),
+//	    WithMaxBackups(14),
+//	    WithCompress(),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	defer w.Close()
+//
+//	log.SetOutput(w)
+func NewRotatingWriter(dir, layout string, opts ...RotateOption) (*RotatingWriter, error) {
+	return newRotatingWriter(dir, layout, time.Now, opts...)
+}
+
+// newRotatingWriter is NewRotatingWriter with a custom clock.
+func newRotatingWriter(dir, layout string, now func() time.Time, opts ...RotateOption) (*RotatingWriter, error) {
+	o := newRotateOptions(opts...)
+
+	w := &RotatingWriter{
+		dir:    dir,
+		layout: layout,
+		opts:   o,
+		now:    now,
+	}
+
+	if err := w.open(w.currentName()); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes p to the current file, rotating it first when the time
+// period changed or p would make it exceed the max size. A single write
+// larger than the max size goes to a fresh file as a whole. If the new file
+// of a rotation cannot be opened, the write fails and the next one retries.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	name := w.currentName()
+
+	switch {
+	case name != w.name:
+		if err := w.rotate(name, false); err != nil {
+			return 0, err
+		}
+	case w.file == nil:
+		if err := w.open(name); err != nil {
+			return 0, err
+		}
+	case w.opts.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.maxSize:
+		if err := w.rotate(name, true); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Rotate forces a rotation of the current file, e.g. on SIGHUP.
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	name := w.currentName()
+
+	return w.rotate(name, name == w.name)
+}
+
+// Sync commits the current file to disk.
+func (w *RotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.file.Sync()
+}
+
+// Close closes the current file and waits for the background compression
+// and pruning to finish. It returns the first error that occurred in the
+// background.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+
+	if w.closed {
+		w.mu.Unlock()
+
+		return os.ErrClosed
+	}
+
+	w.closed = true
+
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+
+	w.mu.Unlock()
+
+	// The background work takes mu to read the current name, so wait
+	// without holding it.
+	w.wg.Wait()
+
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+
+	if err == nil {
+		err = w.err
+	}
+
+	return err
+}
+
+// currentName returns the path of the file to write to right now.
+func (w *RotatingWriter) currentName() string {
+	return filepath.Join(w.dir, w.now().Format(w.layout))
+}
+
+// open opens name for appending and makes it the current file.
+func (w *RotatingWriter) open(name string) error {
+	if err := MkdirAll(filepath.Dir(name)); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, w.opts.fileMode)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	w.file, w.name, w.size = file, name, info.Size()
+
+	return nil
+}
+
+// rotate closes the current file and opens name. With rename set the
+// current file is renamed to a timestamped backup first, which is needed
+// when name equals the current name. The current file is left nil when
+// name cannot be opened, so that the next write retries.
+func (w *RotatingWriter) rotate(name string, rename bool) error {
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+
+		if err != nil {
+			return err
+		}
+	}
+
+	backup := w.name
+
+	if rename {
+		backup = w.backupName()
+
+		if err := os.Rename(w.name, backup); err != nil {
+			// Keep writing to the old file rather than failing every write.
+			if openErr := w.open(w.name); openErr != nil {
+				return errors.Join(err, openErr)
+			}
+
+			return err
+		}
+	}
+
+	if err := w.open(name); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+
+	go w.cleanup(backup)
+
+	return nil
+}
+
+// backupName returns a free name for a size rotated backup of the current
+// file.
+func (w *RotatingWriter) backupName() string {
+	now := w.now()
+
+	for {
+		name := w.name + "." + now.Format(backupLayout)
+		if !pathExists(name) && !pathExists(name+compressSuffix) {
+			return name
+		}
+
+		now = now.Add(time.Millisecond)
+	}
+}
+
+// cleanup compresses the fresh backup and removes backups over the limit.
+func (w *RotatingWriter) cleanup(backup string) {
+	defer w.wg.Done()
+
+	w.mill.Lock()
+	defer w.mill.Unlock()
+
+	if w.opts.compress {
+		if err := compressFile(backup); err != nil {
+			w.fail(err)
+		}
+	}
+
+	if err := w.prune(); err != nil {
+		w.fail(err)
+	}
+}
+
+// prune removes the oldest backups beyond the max backups count.
+func (w *RotatingWriter) prune() error {
+	if w.opts.maxBackups < 1 {
+		return nil
+	}
+
+	w.mu.Lock()
+	current := w.name
+	w.mu.Unlock()
+
+	dir := filepath.Dir(current)
+
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	backups := make([]fs.FileInfo, 0)
+
+	for _, item := range items {
+		if !item.Type().IsRegular() || filepath.Join(dir, item.Name()) == current || !w.isBackup(item.Name()) {
+			continue
+		}
+
+		info, err := item.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return err
+		}
+
+		backups = append(backups, info)
+	}
+
+	if len(backups) <= w.opts.maxBackups {
+		return nil
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+
+	for _, info := range backups[w.opts.maxBackups:] {
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isBackup reports whether the file name was produced by the writer.
+func (w *RotatingWriter) isBackup(name string) bool {
+	name = strings.TrimSuffix(name, compressSuffix)
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		if _, err := time.Parse(backupLayout, name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+
+	_, err := time.Parse(filepath.Base(w.layout), name)
+
+	return err == nil
+}
+
+func (w *RotatingWriter) fail(err error) {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// compressFile gzips name to name.gz keeping its mode and modification time
+// and removes name.
+func compressFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = writeAtomic(name+compressSuffix, info.Mode().Perm(), func(f *os.File) error {
+		gz := gzip.NewWriter(f)
+
+		if _, err := io.Copy(gz, in); err != nil {
+			return err
+		}
+
+		if err := gz.Close(); err != nil {
+			return err
+		}
+
+		return os.Chtimes(f.Name(), accessTime(info), info.ModTime())
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+// pathExists reports whether anything exists at name.
+func pathExists(name string) bool {
+	_, err := os.Lstat(name)
+
+	return err == nil
+}
@@ -0,0 +1,285 @@
+package files
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for RotatingWriter tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func newTestRotatingWriter(t *testing.T, dir, layout string, opts ...RotateOption) (*RotatingWriter, *fakeClock) {
+	t.Helper()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 10, 0, 0, 0, time.Local)}
+
+	w, err := newRotatingWriter(dir, layout, clock.Now, opts...)
+	require.NoError(t, err)
+
+	return w, clock
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	items, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name())
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func readGzip(t *testing.T, name string) string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestRotatingWriter(t *testing.T) {
+	t.Run("appends to existing file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), []byte("old\n"), 0o644))
+
+		w, err := NewRotatingWriter(dir, "app.log")
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte("new\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "old\nnew\n", string(data))
+	})
+
+	t.Run("size rotation", func(t *testing.T) {
+		dir := t.TempDir()
+		w, clock := newTestRotatingWriter(t, dir, "app.log", WithMaxSize(10))
+
+		for _, line := range []string{"12345\n", "67890\n", "abcde\n"} {
+			_, err := w.Write([]byte(line))
+			require.NoError(t, err)
+			clock.Add(time.Second)
+		}
+
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{
+			"app.log",
+			"app.log.20240102T100001,000",
+			"app.log.20240102T100002,000",
+		}, dirNames(t, dir))
+
+		data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "abcde\n", string(data))
+
+		data, err = os.ReadFile(filepath.Join(dir, "app.log.20240102T100001,000"))
+		require.NoError(t, err)
+		assert.Equal(t, "12345\n", string(data))
+	})
+
+	t.Run("backup name collision", func(t *testing.T) {
+		dir := t.TempDir()
+		w, _ := newTestRotatingWriter(t, dir, "app.log")
+
+		require.NoError(t, w.Rotate())
+		require.NoError(t, w.Rotate())
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{
+			"app.log",
+			"app.log.20240102T100000,000",
+			"app.log.20240102T100000,001",
+		}, dirNames(t, dir))
+	})
+
+	t.Run("time rotation", func(t *testing.T) {
+		dir := t.TempDir()
+		w, clock := newTestRotatingWriter(t, dir, "app-2006-01-02.log")
+
+		_, err := w.Write([]byte("day one\n"))
+		require.NoError(t, err)
+
+		clock.Add(24 * time.Hour)
+
+		_, err = w.Write([]byte("day two\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{"app-2024-01-02.log", "app-2024-01-03.log"}, dirNames(t, dir))
+	})
+
+	t.Run("time rotation retries open", func(t *testing.T) {
+		dir := t.TempDir()
+		w, clock := newTestRotatingWriter(t, dir, "app-2006-01-02.log")
+
+		_, err := w.Write([]byte("day one\n"))
+		require.NoError(t, err)
+
+		// A directory in place of the new file makes the open fail.
+		blocker := filepath.Join(dir, "app-2024-01-03.log")
+		require.NoError(t, os.Mkdir(blocker, 0o755))
+
+		clock.Add(24 * time.Hour)
+
+		_, err = w.Write([]byte("lost\n"))
+		require.Error(t, err)
+		require.NoError(t, w.Sync())
+
+		require.NoError(t, os.Remove(blocker))
+
+		_, err = w.Write([]byte("day two\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		data, err := os.ReadFile(blocker)
+		require.NoError(t, err)
+		assert.Equal(t, "day two\n", string(data))
+
+		data, err = os.ReadFile(filepath.Join(dir, "app-2024-01-02.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "day one\n", string(data))
+	})
+
+	t.Run("compress and prune", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "other.txt"), []byte("keep"), 0o644))
+
+		w, clock := newTestRotatingWriter(t, dir, "app-2006-01-02.log", WithMaxBackups(2), WithCompress())
+
+		for day := range 5 {
+			_, err := w.Write([]byte(strings.Repeat("x", day+1)))
+			require.NoError(t, err)
+
+			clock.Add(24 * time.Hour)
+
+			// Distinct modification times for pruning order.
+			w.wg.Wait()
+
+			mtime := time.Now().Add(time.Duration(day-10) * time.Minute)
+			for _, name := range dirNames(t, dir) {
+				if strings.HasSuffix(name, ".gz") {
+					continue
+				}
+
+				require.NoError(t, os.Chtimes(filepath.Join(dir, name), mtime, mtime))
+			}
+		}
+
+		_, err := w.Write([]byte("today"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, []string{
+			"app-2024-01-05.log.gz",
+			"app-2024-01-06.log.gz",
+			"app-2024-01-07.log",
+			"other.txt",
+		}, dirNames(t, dir))
+
+		assert.Equal(t, "xxxxx", readGzip(t, filepath.Join(dir, "app-2024-01-06.log.gz")))
+	})
+
+	t.Run("file mode", func(t *testing.T) {
+		dir := t.TempDir()
+
+		w, err := NewRotatingWriter(filepath.Join(dir, "logs"), "app.log", WithRotateFileMode(0o600))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		info, err := os.Stat(filepath.Join(dir, "logs", "app.log"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		dir := t.TempDir()
+
+		w, err := NewRotatingWriter(dir, "app.log", WithMaxSize(100))
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for range 8 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for range 50 {
+					_, err := w.Write([]byte("0123456789\n"))
+					assert.NoError(t, err)
+				}
+			}()
+		}
+
+		wg.Wait()
+		require.NoError(t, w.Close())
+
+		total := 0
+		for _, name := range dirNames(t, dir) {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(data), 100)
+			total += len(data)
+		}
+
+		assert.Equal(t, 8*50*11, total)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		w, err := NewRotatingWriter(t.TempDir(), "app.log")
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		_, err = w.Write([]byte("x"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+		assert.ErrorIs(t, w.Close(), os.ErrClosed)
+		assert.ErrorIs(t, w.Rotate(), os.ErrClosed)
+		assert.ErrorIs(t, w.Sync(), os.ErrClosed)
+	})
+}
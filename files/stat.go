@@ -0,0 +1,134 @@
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// ErrStatUnsupported is returned by Stat on platforms whose file information
+// does not expose owners, inodes and times.
+var ErrStatUnsupported = errors.New("extended file info is not supported on this platform")
+
+// FileInfo is the extended information about a file returned by Stat.
+type FileInfo struct {
+	// Path is the name passed to Stat.
+	Path string
+
+	// Size is the file size in bytes.
+	Size int64
+
+	// Mode holds the permission and type bits.
+	Mode fs.FileMode
+
+	// UID and GID are the numeric owner and group.
+	UID uint32
+	GID uint32
+
+	// User and Group are the names of the owner and group, empty when they
+	// cannot be looked up.
+	User  string
+	Group string
+
+	// Dev is the device holding the file and Inode its number on it.
+	Dev   uint64
+	Inode uint64
+
+	// Nlink is the number of hard links.
+	Nlink uint64
+
+	// AccessTime, ModTime and ChangeTime are the last access, content
+	// modification and metadata change times.
+	AccessTime time.Time
+	ModTime    time.Time
+	ChangeTime time.Time
+
+	// BirthTime is the creation time, zero when the filesystem or the kernel
+	// does not report it.
+	BirthTime time.Time
+}
+
+// IsDir reports whether the file is a directory.
+func (i *FileInfo) IsDir() bool {
+	return i.Mode.IsDir()
+}
+
+// HasBirthTime reports whether the creation time is known.
+func (i *FileInfo) HasBirthTime() bool {
+	return !i.BirthTime.IsZero()
+}
+
+// Stat returns the extended information about the file name, following
+// symbolic links. On Linux the birth time is read with statx(2) where the
+// kernel supports it, falling back to stat(2) otherwise.
+//
+// Example usage:
+//
+//	info, err := Stat("/var/lib/bot/world.dat")
+//	if err != nil {
+//	    return err
+//	}
+//	if info.HasBirthTime() {
+//	    log.Printf("created %s by %s", info.BirthTime, info.User)
+//	}
+func Stat(name string) (*FileInfo, error) {
+	return statOwner(name, true)
+}
+
+// Lstat is Stat describing symbolic links themselves.
+func Lstat(name string) (*FileInfo, error) {
+	return statOwner(name, false)
+}
+
+// statOwner is statFile with the user and group names looked up.
+func statOwner(name string, follow bool) (*FileInfo, error) {
+	fi, err := statFile(name, follow)
+	if err != nil {
+		return nil, err
+	}
+
+	lookupOwner(fi)
+
+	return fi, nil
+}
+
+// newFileInfo converts the file info returned by os.Stat or os.Lstat. The
+// owner names are left empty.
+func newFileInfo(name string, info fs.FileInfo) (*FileInfo, error) {
+	fi := &FileInfo{
+		Path:    name,
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+	}
+
+	if !fillSys(fi, info.Sys()) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: ErrStatUnsupported}
+	}
+
+	return fi, nil
+}
+
+// lookupOwner sets the user and group names of fi.
+func lookupOwner(fi *FileInfo) {
+	if u, err := user.LookupId(strconv.FormatUint(uint64(fi.UID), 10)); err == nil {
+		fi.User = u.Username
+	}
+
+	if g, err := user.LookupGroupId(strconv.FormatUint(uint64(fi.GID), 10)); err == nil {
+		fi.Group = g.Name
+	}
+}
+
+// accessTime returns the last access time of info or its modification time
+// when the platform does not expose it.
+func accessTime(info fs.FileInfo) time.Time {
+	var fi FileInfo
+	if fillSys(&fi, info.Sys()) {
+		return fi.AccessTime
+	}
+
+	return info.ModTime()
+}
@@ -0,0 +1,42 @@
+//go:build darwin
+
+package files
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func statFile(name string, follow bool) (*FileInfo, error) {
+	stat := os.Stat
+	if !follow {
+		stat = os.Lstat
+	}
+
+	info, err := stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileInfo(name, info)
+}
+
+// fillSys sets the fields of fi found in the stat structure sys.
+func fillSys(fi *FileInfo, sys any) bool {
+	stat, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	fi.UID = stat.Uid
+	fi.GID = stat.Gid
+	fi.Dev = uint64(stat.Dev) //nolint:gosec // device numbers are not negative
+	fi.Inode = stat.Ino
+	fi.Nlink = uint64(stat.Nlink)
+	fi.AccessTime = time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec)
+	fi.ChangeTime = time.Unix(stat.Ctimespec.Sec, stat.Ctimespec.Nsec)
+	fi.BirthTime = time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec)
+
+	return true
+}
@@ -0,0 +1,134 @@
+//go:build linux
+
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func statFile(name string, follow bool) (*FileInfo, error) {
+	flags := unix.AT_STATX_SYNC_AS_STAT
+	if !follow {
+		flags |= unix.AT_SYMLINK_NOFOLLOW
+	}
+
+	var (
+		stx unix.Statx_t
+		err error
+	)
+
+	for {
+		err = unix.Statx(unix.AT_FDCWD, name, flags, unix.STATX_BASIC_STATS|unix.STATX_BTIME, &stx)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+
+	// statx(2) appeared in Linux 4.11 and is blocked by some seccomp
+	// profiles, fall back to stat(2) without the birth time.
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EPERM) {
+		return statFallback(name, follow)
+	}
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "statx", Path: name, Err: err}
+	}
+
+	fi := &FileInfo{
+		Path:       name,
+		Size:       int64(stx.Size), //nolint:gosec // sizes fit in int64
+		Mode:       fileMode(uint32(stx.Mode)),
+		UID:        stx.Uid,
+		GID:        stx.Gid,
+		Dev:        unix.Mkdev(stx.Dev_major, stx.Dev_minor),
+		Inode:      stx.Ino,
+		Nlink:      uint64(stx.Nlink),
+		AccessTime: statxTime(stx.Atime),
+		ModTime:    statxTime(stx.Mtime),
+		ChangeTime: statxTime(stx.Ctime),
+	}
+
+	if stx.Mask&unix.STATX_BTIME != 0 {
+		fi.BirthTime = statxTime(stx.Btime)
+	}
+
+	return fi, nil
+}
+
+func statFallback(name string, follow bool) (*FileInfo, error) {
+	stat := os.Stat
+	if !follow {
+		stat = os.Lstat
+	}
+
+	info, err := stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileInfo(name, info)
+}
+
+// fillSys sets the fields of fi found in the stat structure sys.
+func fillSys(fi *FileInfo, sys any) bool {
+	stat, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	// The field types differ between architectures.
+	fi.UID = stat.Uid
+	fi.GID = stat.Gid
+	fi.Dev = uint64(stat.Dev) //nolint:unconvert // not uint64 everywhere
+	fi.Inode = stat.Ino
+	fi.Nlink = uint64(stat.Nlink) //nolint:unconvert // not uint64 everywhere
+
+	fi.AccessTime = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)) //nolint:unconvert // not int64 everywhere
+	fi.ChangeTime = time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec)) //nolint:unconvert // not int64 everywhere
+
+	return true
+}
+
+func statxTime(ts unix.StatxTimestamp) time.Time {
+	return time.Unix(ts.Sec, int64(ts.Nsec))
+}
+
+// fileMode converts the st_mode bits to fs.FileMode the way os.Stat does it.
+func fileMode(mode uint32) fs.FileMode {
+	m := fs.FileMode(mode & 0o777)
+
+	switch mode & unix.S_IFMT {
+	case unix.S_IFBLK:
+		m |= fs.ModeDevice
+	case unix.S_IFCHR:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case unix.S_IFDIR:
+		m |= fs.ModeDir
+	case unix.S_IFIFO:
+		m |= fs.ModeNamedPipe
+	case unix.S_IFLNK:
+		m |= fs.ModeSymlink
+	case unix.S_IFSOCK:
+		m |= fs.ModeSocket
+	}
+
+	if mode&unix.S_ISGID != 0 {
+		m |= fs.ModeSetgid
+	}
+
+	if mode&unix.S_ISUID != 0 {
+		m |= fs.ModeSetuid
+	}
+
+	if mode&unix.S_ISVTX != 0 {
+		m |= fs.ModeSticky
+	}
+
+	return m
+}
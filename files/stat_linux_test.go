@@ -0,0 +1,36 @@
+//go:build linux
+
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatFallback(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "setuid")
+	require.NoError(t, os.WriteFile(name, []byte("x"), 0o644))
+	require.NoError(t, os.Chmod(name, os.ModeSetuid|os.ModeSetgid|0o755))
+	require.NoError(t, os.Symlink("setuid", filepath.Join(dir, "link")))
+
+	for _, path := range []string{name, dir, filepath.Join(dir, "link"), "/dev/null"} {
+		for _, follow := range []bool{true, false} {
+			statx, err := statFile(path, follow)
+			require.NoError(t, err)
+
+			fallback, err := statFallback(path, follow)
+			require.NoError(t, err)
+
+			// The fallback has no birth time, the access time may have
+			// changed in between.
+			statx.BirthTime = fallback.BirthTime
+			statx.AccessTime = fallback.AccessTime
+			assert.Equal(t, fallback, statx, path)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux && !darwin
+
+package files
+
+import (
+	"io/fs"
+	"os"
+)
+
+func statFile(name string, follow bool) (*FileInfo, error) {
+	stat := os.Stat
+	if !follow {
+		stat = os.Lstat
+	}
+
+	if _, err := stat(name); err != nil {
+		return nil, err
+	}
+
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: ErrStatUnsupported}
+}
+
+// fillSys reports that the stat structure is not known on this platform.
+func fillSys(_ *FileInfo, _ any) bool {
+	return false
+}
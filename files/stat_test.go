@@ -0,0 +1,84 @@
+package files
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStat(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "world.dat")
+
+	before := time.Now().Add(-time.Second)
+	require.NoError(t, os.WriteFile(name, []byte("world"), 0o640))
+	require.NoError(t, os.Chmod(name, 0o640))
+	require.NoError(t, os.Link(name, filepath.Join(dir, "hardlink")))
+	require.NoError(t, os.Symlink("world.dat", filepath.Join(dir, "symlink")))
+
+	mtime := time.Date(2021, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	atime := mtime.Add(time.Hour)
+	require.NoError(t, os.Chtimes(name, atime, mtime))
+
+	t.Run("regular file", func(t *testing.T) {
+		info, err := Stat(name)
+		require.NoError(t, err)
+
+		assert.Equal(t, name, info.Path)
+		assert.Equal(t, int64(5), info.Size)
+		assert.Equal(t, fs.FileMode(0o640), info.Mode)
+		assert.False(t, info.IsDir())
+		assert.Equal(t, uint64(2), info.Nlink)
+		assert.NotZero(t, info.Inode)
+		assert.True(t, info.ModTime.Equal(mtime), info.ModTime)
+		assert.True(t, info.AccessTime.Equal(atime), info.AccessTime)
+		assert.True(t, info.ChangeTime.After(before), info.ChangeTime)
+
+		assert.Equal(t, uint32(os.Getuid()), info.UID) //nolint:gosec // uids are not negative
+		assert.Equal(t, uint32(os.Getgid()), info.GID) //nolint:gosec // gids are not negative
+
+		if u, err := user.LookupId(strconv.Itoa(os.Getuid())); err == nil {
+			assert.Equal(t, u.Username, info.User)
+		}
+
+		if info.HasBirthTime() {
+			assert.True(t, info.BirthTime.After(before), info.BirthTime)
+		}
+
+		linked, err := Stat(filepath.Join(dir, "hardlink"))
+		require.NoError(t, err)
+		assert.Equal(t, info.Inode, linked.Inode)
+		assert.Equal(t, info.Dev, linked.Dev)
+	})
+
+	t.Run("symbolic link", func(t *testing.T) {
+		info, err := Stat(filepath.Join(dir, "symlink"))
+		require.NoError(t, err)
+		assert.True(t, info.Mode.IsRegular())
+
+		info, err = Lstat(filepath.Join(dir, "symlink"))
+		require.NoError(t, err)
+		assert.Equal(t, fs.ModeSymlink, info.Mode.Type())
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		require.NoError(t, os.Chmod(dir, os.ModeSticky|0o750))
+
+		info, err := Stat(dir)
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, fs.ModeDir|fs.ModeSticky|0o750, info.Mode)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Stat(filepath.Join(dir, "missing"))
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
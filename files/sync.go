@@ -0,0 +1,453 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// ErrTypeConflict indicates that an entry of the destination has another
+// type than the source one, e.g. a directory in place of a file, and may not
+// be removed to replace it.
+var ErrTypeConflict = errors.New("file type conflict")
+
+// SyncDiff lists the slash separated paths, relative to the synced roots,
+// that SyncDir added, changed or removed in the destination.
+type SyncDiff struct {
+	// Added holds the files, links and directories missing in the destination.
+	Added []string
+
+	// Changed holds the entries whose content or type differed.
+	Changed []string
+
+	// Removed holds the extra entries of the destination. A directory removed
+	// as a whole is listed once, without its content.
+	Removed []string
+}
+
+// Empty reports whether the destination was already in sync.
+func (d *SyncDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// SyncDir makes the directory dst a mirror of src, copying only what is
+// missing or differs, and returns what was changed.
+//
+// Parameters:
+//   - ctx: stops the sync when done
+//   - src: source directory
+//   - dst: destination directory, created with its parents if missing
+//   - opts: comparison, filter and copy options
+//
+// Returns:
+//   - *SyncDiff: applied changes, also returned along with an error for the
+//     changes applied before it occurred
+//   - error: ErrNotDir, ErrUnsafePath, ErrTypeConflict, comparison or copy
+//     error
+//
+// Behavior details:
+//   - Files are equal when their size and modification time match, or their
+//     size and hash with WithChecksum. Symbolic links are equal when they
+//     point to the same path and are never followed
+//   - Changed files are replaced atomically the same way Copy does it.
+//     Modification times are always preserved, they are what the next run
+//     compares
+//   - WithSyncInclude and WithSyncExclude select the synced entries. Entries
+//     of dst not selected by them are neither changed nor removed
+//   - WithDelete removes the selected entries of dst missing in src. Extra
+//     directories are descended into and removed once they are left empty
+//   - An entry of dst with another type than in src is replaced only with
+//     WithDelete and only if the filters select all of it, otherwise the
+//     sync stops with ErrTypeConflict
+//   - WithDryRun only reports what would be changed
+//   - Refuses overlapping src and dst, and with WithDelete a dst of "/" or
+//     the home directory, with ErrUnsafePath
+//
+// Example usage:
+//
+//	// Deploy a config bundle, dropping files removed from it.
+//	diff, err := SyncDir(ctx, "/opt/bundle/conf", "/etc/myapp",
+//	    WithSyncExclude("*.local.yaml"),
+//	    WithDelete(),
+//	)
+//	if err == nil && !diff.Empty() {
+//	    reload()
+//	}
+//
+// Warning:
+//   - With WithDelete this is a destructive operation, run it with
+//     WithDryRun first.
+func SyncDir(ctx context.Context, src, dst string, opts ...SyncOption) (*SyncDiff, error) {
+	o := newSyncOptions(opts...)
+
+	if o.checksum {
+		if _, err := o.hash.New(); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "sync", Path: src, Err: ErrNotDir}
+	}
+
+	if err := checkSyncPaths(src, dst, o.delete); err != nil {
+		return nil, err
+	}
+
+	dstInfo, err := os.Stat(dst)
+	if err == nil && !dstInfo.IsDir() {
+		return nil, &fs.PathError{Op: "sync", Path: dst, Err: ErrNotDir}
+	}
+
+	created := errors.Is(err, fs.ErrNotExist)
+
+	if !o.dryRun {
+		if err := MkdirAll(dst, info.Mode().Perm()|0o700); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &syncer{
+		ctx:  ctx,
+		root: src,
+		opts: o,
+		copy: &copyOptions{preserveTimes: true},
+		diff: &SyncDiff{
+			Added:   make([]string, 0),
+			Changed: make([]string, 0),
+			Removed: make([]string, 0),
+		},
+	}
+
+	err = s.syncDir(src, dst, info, created)
+
+	for _, paths := range [][]string{s.diff.Added, s.diff.Changed, s.diff.Removed} {
+		sort.Strings(paths)
+	}
+
+	return s.diff, err
+}
+
+// syncer holds the state of a SyncDir call.
+type syncer struct {
+	ctx  context.Context //nolint:containedctx // scoped to a single sync
+	root string
+	opts *syncOptions
+	copy *copyOptions
+	diff *SyncDiff
+}
+
+func (s *syncer) syncDir(src, dst string, info fs.FileInfo, created bool) error {
+	items, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{}, len(items))
+
+	for _, item := range items {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+
+		seen[item.Name()] = struct{}{}
+
+		full := filepath.Join(src, item.Name())
+		target := filepath.Join(dst, item.Name())
+
+		if err := s.syncItem(full, target, s.rel(full)); err != nil {
+			return err
+		}
+	}
+
+	if s.opts.delete {
+		if err := s.removeExtra(src, dst, seen); err != nil {
+			return err
+		}
+	}
+
+	if s.opts.dryRun {
+		return nil
+	}
+
+	return applyDirMetadata(dst, info, s.copy, created)
+}
+
+// syncItem brings target in line with the item src of the source tree.
+func (s *syncer) syncItem(src, target, rel string) error {
+	if s.opts.excluded(rel) {
+		return nil
+	}
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	mode := info.Mode()
+	if !mode.IsDir() && !mode.IsRegular() && mode&fs.ModeSymlink == 0 {
+		return nil
+	}
+
+	if !mode.IsDir() && !s.opts.selected(rel) {
+		return nil
+	}
+
+	current, err := s.lstat(target)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case current == nil:
+		s.diff.Added = append(s.diff.Added, rel)
+	case current.Mode().Type() != mode.Type():
+		if err := s.clear(target, rel); err != nil {
+			return err
+		}
+
+		s.diff.Changed = append(s.diff.Changed, rel)
+		current = nil
+	case mode.IsDir():
+	default:
+		changed, err := s.changed(src, target, info, current)
+		if err != nil || !changed {
+			return err
+		}
+
+		s.diff.Changed = append(s.diff.Changed, rel)
+	}
+
+	if mode.IsDir() {
+		if current == nil && !s.opts.dryRun {
+			// The owner needs write access to fill the directory.
+			if err := os.Mkdir(target, mode.Perm()|0o700); err != nil {
+				return err
+			}
+		}
+
+		return s.syncDir(src, target, info, current == nil)
+	}
+
+	if s.opts.dryRun {
+		return nil
+	}
+
+	if mode.IsRegular() {
+		return copyFile(s.ctx, src, target, info, s.copy)
+	}
+
+	return copySymlink(src, target, info, s.copy)
+}
+
+// clear removes the entry target of dst standing in the way of a source
+// entry of another type.
+func (s *syncer) clear(target, rel string) error {
+	if !s.opts.delete {
+		return &fs.PathError{Op: "sync", Path: target, Err: ErrTypeConflict}
+	}
+
+	_, whole, err := s.removeEntry(target, rel)
+	if err != nil {
+		return err
+	}
+
+	if !whole {
+		return &fs.PathError{Op: "sync", Path: target, Err: ErrTypeConflict}
+	}
+
+	return nil
+}
+
+// changed compares a regular file or a symbolic link with its copy of the
+// same type.
+func (s *syncer) changed(src, dst string, info, current fs.FileInfo) (bool, error) {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return false, err
+		}
+
+		currentLink, err := os.Readlink(dst)
+		if err != nil {
+			return false, err
+		}
+
+		return link != currentLink, nil
+	}
+
+	if info.Size() != current.Size() {
+		return true, nil
+	}
+
+	if !s.opts.checksum {
+		return !info.ModTime().Equal(current.ModTime()), nil
+	}
+
+	sum, err := HashFile(s.ctx, src, s.opts.hash)
+	if err != nil {
+		return false, err
+	}
+
+	currentSum, err := HashFile(s.ctx, dst, s.opts.hash)
+	if err != nil {
+		return false, err
+	}
+
+	return sum != currentSum, nil
+}
+
+// removeExtra removes the selected entries of dst missing in its source
+// directory src.
+func (s *syncer) removeExtra(src, dst string, seen map[string]struct{}) error {
+	items, err := os.ReadDir(dst)
+	if err != nil {
+		// In dry-run mode dst may not have been created yet.
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, item := range items {
+		if _, ok := seen[item.Name()]; ok {
+			continue
+		}
+
+		removed, _, err := s.removeEntry(filepath.Join(dst, item.Name()), s.rel(filepath.Join(src, item.Name())))
+		s.diff.Removed = append(s.diff.Removed, removed...)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeEntry removes the extra entry full of dst if it is selected. A
+// directory is descended into and removed only once it is left empty.
+// It returns the removed paths, just rel if the entry went as a whole.
+func (s *syncer) removeEntry(full, rel string) ([]string, bool, error) {
+	if s.opts.excluded(rel) {
+		return nil, false, nil
+	}
+
+	info, err := os.Lstat(full)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	if !info.IsDir() {
+		if !s.opts.selected(rel) {
+			return nil, false, nil
+		}
+
+		if !s.opts.dryRun {
+			if err := os.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, false, err
+			}
+		}
+
+		return []string{rel}, true, nil
+	}
+
+	items, err := os.ReadDir(full)
+	if err != nil {
+		return nil, false, err
+	}
+
+	removed := make([]string, 0)
+	whole := true
+
+	for _, item := range items {
+		if err := s.ctx.Err(); err != nil {
+			return removed, false, err
+		}
+
+		paths, ok, err := s.removeEntry(filepath.Join(full, item.Name()), path.Join(rel, item.Name()))
+		removed = append(removed, paths...)
+
+		if err != nil {
+			return removed, false, err
+		}
+
+		whole = whole && ok
+	}
+
+	if !whole {
+		return removed, false, nil
+	}
+
+	if !s.opts.dryRun {
+		if err := os.Remove(full); err != nil {
+			return removed, false, err
+		}
+	}
+
+	return []string{rel}, true, nil
+}
+
+// lstat returns the file info of a destination path or nil if it does not
+// exist. In dry-run mode a parent may still be a file, that counts as missing.
+func (s *syncer) lstat(name string) (fs.FileInfo, error) {
+	info, err := os.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return nil, nil //nolint:nilnil // a missing file is not an error here
+	}
+
+	return info, err
+}
+
+// rel returns the slash separated path of full relative to the source root.
+func (s *syncer) rel(full string) string {
+	rel, err := filepath.Rel(s.root, full)
+	if err != nil {
+		return filepath.ToSlash(full)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// checkSyncPaths refuses syncing a tree into itself or into one of its
+// subdirectories, which would never end or delete the source.
+func checkSyncPaths(src, dst string, remove bool) error {
+	srcPath, err := resolvePath(src)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := resolvePath(dst)
+	if err != nil {
+		return err
+	}
+
+	if pathWithin(srcPath, dstPath) || pathWithin(dstPath, srcPath) {
+		return &fs.PathError{Op: "sync", Path: dst, Err: ErrUnsafePath}
+	}
+
+	if _, err := os.Stat(dst); remove && err == nil {
+		return checkSafePath(dst)
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSyncTest(t *testing.T) (string, string) {
+	t.Helper()
+
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	dst := filepath.Join(base, "dst")
+
+	files := map[string]string{
+		"app.yaml":          "port: 80\n",
+		"app.local.yaml":    "debug: true\n",
+		"conf.d/db.yaml":    "host: db\n",
+		"conf.d/cache.yaml": "host: cache\n",
+	}
+
+	for name, content := range files {
+		full := filepath.Join(src, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+	}
+
+	require.NoError(t, os.Symlink("conf.d/db.yaml", filepath.Join(src, "db.yaml")))
+
+	return src, dst
+}
+
+func TestSyncDir(t *testing.T) {
+	ctx := context.Background()
+	src, dst := setupSyncTest(t)
+
+	diff, err := SyncDir(ctx, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.local.yaml", "app.yaml", "conf.d", "conf.d/cache.yaml", "conf.d/db.yaml", "db.yaml"}, diff.Added)
+	assert.Empty(t, diff.Changed)
+	assert.Empty(t, diff.Removed)
+
+	link, err := os.Readlink(filepath.Join(dst, "db.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "conf.d/db.yaml", link)
+
+	t.Run("in sync", func(t *testing.T) {
+		diff, err := SyncDir(ctx, src, dst)
+		require.NoError(t, err)
+		assert.True(t, diff.Empty())
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		// Same size, different content and time.
+		require.NoError(t, os.WriteFile(filepath.Join(src, "app.yaml"), []byte("port: 81\n"), 0o644))
+		later := time.Now().Add(time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(src, "app.yaml"), later, later))
+
+		require.NoError(t, os.Remove(filepath.Join(src, "db.yaml")))
+		require.NoError(t, os.Symlink("conf.d/cache.yaml", filepath.Join(src, "db.yaml")))
+
+		require.NoError(t, os.Remove(filepath.Join(src, "conf.d", "cache.yaml")))
+		require.NoError(t, os.WriteFile(filepath.Join(src, "new.yaml"), []byte("new\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dst, "extra.yaml"), []byte("extra\n"), 0o644))
+
+		diff, err := SyncDir(ctx, src, dst, WithDryRun(), WithDelete())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new.yaml"}, diff.Added)
+		assert.Equal(t, []string{"app.yaml", "db.yaml"}, diff.Changed)
+		assert.Equal(t, []string{"conf.d/cache.yaml", "extra.yaml"}, diff.Removed)
+		assert.NoFileExists(t, filepath.Join(dst, "new.yaml"))
+		assert.FileExists(t, filepath.Join(dst, "extra.yaml"))
+
+		applied, err := SyncDir(ctx, src, dst, WithDelete())
+		require.NoError(t, err)
+		assert.Equal(t, diff, applied)
+
+		data, err := os.ReadFile(filepath.Join(dst, "app.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "port: 81\n", string(data))
+
+		info, err := os.Stat(filepath.Join(dst, "app.yaml"))
+		require.NoError(t, err)
+		assert.True(t, info.ModTime().Equal(later))
+
+		assert.NoFileExists(t, filepath.Join(dst, "extra.yaml"))
+		assert.NoFileExists(t, filepath.Join(dst, "conf.d", "cache.yaml"))
+
+		link, err := os.Readlink(filepath.Join(dst, "db.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "conf.d/cache.yaml", link)
+	})
+
+	t.Run("extras kept without delete", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dst, "extra.yaml"), []byte("extra\n"), 0o644))
+
+		diff, err := SyncDir(ctx, src, dst)
+		require.NoError(t, err)
+		assert.True(t, diff.Empty())
+		assert.FileExists(t, filepath.Join(dst, "extra.yaml"))
+	})
+}
+
+func TestSyncDirChecksum(t *testing.T) {
+	ctx := context.Background()
+	src, dst := setupSyncTest(t)
+
+	_, err := SyncDir(ctx, src, dst)
+	require.NoError(t, err)
+
+	// Touched but identical files are not copied.
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "app.yaml"), later, later))
+
+	// Same size and time, different content.
+	info, err := os.Stat(filepath.Join(dst, "conf.d", "db.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "conf.d", "db.yaml"), []byte("host: xx\n"), 0o644))
+	require.NoError(t, os.Chtimes(filepath.Join(dst, "conf.d", "db.yaml"), info.ModTime(), info.ModTime()))
+
+	diff, err := SyncDir(ctx, src, dst, WithDryRun())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.yaml"}, diff.Changed)
+
+	diff, err = SyncDir(ctx, src, dst, WithChecksum(HashSHA256))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conf.d/db.yaml"}, diff.Changed)
+
+	data, err := os.ReadFile(filepath.Join(dst, "conf.d", "db.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "host: db\n", string(data))
+
+	_, err = SyncDir(ctx, src, dst, WithChecksum(HashAlgorithm(42)))
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestSyncDirFilters(t *testing.T) {
+	ctx := context.Background()
+	src, dst := setupSyncTest(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "conf.d"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "app.local.yaml"), []byte("mine\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "conf.d", "old.yaml"), []byte("old\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "README"), []byte("readme\n"), 0o644))
+
+	diff, err := SyncDir(ctx, src, dst, WithSyncExclude("*.local.yaml"), WithSyncInclude("*.yaml"), WithDelete())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.yaml", "conf.d/cache.yaml", "conf.d/db.yaml", "db.yaml"}, diff.Added)
+	assert.Equal(t, []string{"conf.d/old.yaml"}, diff.Removed)
+
+	data, err := os.ReadFile(filepath.Join(dst, "app.local.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "mine\n", string(data))
+	assert.FileExists(t, filepath.Join(dst, "README"))
+
+	t.Run("extra directories", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dst, "certs"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dst, "certs", "server.pem"), []byte("pem\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dst, "certs", "old.yaml"), []byte("old\n"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(dst, "stale", "nested"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dst, "stale", "nested", "a.yaml"), []byte("a\n"), 0o644))
+
+		diff, err := SyncDir(ctx, src, dst, WithSyncInclude("*.yaml"), WithDelete())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"certs/old.yaml", "stale"}, diff.Removed)
+
+		assert.FileExists(t, filepath.Join(dst, "certs", "server.pem"))
+		assert.NoFileExists(t, filepath.Join(dst, "certs", "old.yaml"))
+		assert.NoDirExists(t, filepath.Join(dst, "stale"))
+	})
+}
+
+func TestSyncDirTypeChange(t *testing.T) {
+	ctx := context.Background()
+	src, dst := setupSyncTest(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "app.yaml", "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "conf.d"), []byte("file\n"), 0o644))
+
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(outside, []byte("keep\n"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dst, "app.local.yaml")))
+
+	_, err := SyncDir(ctx, src, dst)
+	require.ErrorIs(t, err, ErrTypeConflict)
+	assert.DirExists(t, filepath.Join(dst, "app.yaml", "nested"))
+
+	diff, err := SyncDir(ctx, src, dst, WithDelete(), WithDryRun())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conf.d/cache.yaml", "conf.d/db.yaml", "db.yaml"}, diff.Added)
+	assert.Equal(t, []string{"app.local.yaml", "app.yaml", "conf.d"}, diff.Changed)
+
+	applied, err := SyncDir(ctx, src, dst, WithDelete())
+	require.NoError(t, err)
+	assert.Equal(t, diff, applied)
+
+	assert.FileExists(t, filepath.Join(dst, "app.yaml"))
+	assert.FileExists(t, filepath.Join(dst, "conf.d", "db.yaml"))
+
+	data, err := os.ReadFile(outside)
+	require.NoError(t, err)
+	assert.Equal(t, "keep\n", string(data))
+}
+
+func TestSyncDirTypeChangeFiltered(t *testing.T) {
+	ctx := context.Background()
+	src, dst := setupSyncTest(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "app.yaml"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "app.yaml", "notes.txt"), []byte("keep\n"), 0o644))
+
+	_, err := SyncDir(ctx, src, dst, WithSyncInclude("*.yaml"), WithDelete())
+	require.ErrorIs(t, err, ErrTypeConflict)
+	assert.FileExists(t, filepath.Join(dst, "app.yaml", "notes.txt"))
+
+	require.NoError(t, os.RemoveAll(filepath.Join(dst, "app.yaml")))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "conf.d"), []byte("keep\n"), 0o644))
+
+	_, err = SyncDir(ctx, src, dst, WithSyncInclude("*.yaml"), WithDelete())
+	require.ErrorIs(t, err, ErrTypeConflict)
+	assert.FileExists(t, filepath.Join(dst, "conf.d"))
+}
+
+func TestSyncDirUnsafe(t *testing.T) {
+	ctx := context.Background()
+	src, dst := setupSyncTest(t)
+
+	_, err := SyncDir(ctx, src, filepath.Join(src, "conf.d", "copy"))
+	assert.ErrorIs(t, err, ErrUnsafePath)
+
+	_, err = SyncDir(ctx, filepath.Join(src, "conf.d"), src)
+	assert.ErrorIs(t, err, ErrUnsafePath)
+
+	_, err = SyncDir(ctx, src, src)
+	assert.ErrorIs(t, err, ErrUnsafePath)
+
+	_, err = SyncDir(ctx, filepath.Join(src, "app.yaml"), dst)
+	assert.ErrorIs(t, err, ErrNotDir)
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		_, err = SyncDir(ctx, src, home, WithDelete(), WithDryRun())
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = SyncDir(canceled, src, dst)
+	assert.ErrorIs(t, err, context.Canceled)
+}
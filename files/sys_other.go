@@ -0,0 +1,31 @@
+//go:build !unix
+
+package files
+
+import (
+	"io/fs"
+	"os"
+)
+
+// fileIdentity reports that files have no inode numbers on this platform.
+func fileIdentity(_ fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
+
+// fileOwner reports that files have no numeric owners on this platform.
+func fileOwner(_ fs.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// processAlive reports whether a process with the given PID exists, as far
+// as os.FindProcess can tell.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	p.Release()
+
+	return true
+}
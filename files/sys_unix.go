@@ -0,0 +1,37 @@
+//go:build unix
+
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of info.
+func fileIdentity(info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true //nolint:unconvert,gosec // not uint64 everywhere
+}
+
+// fileOwner returns the numeric owner and group of info.
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// processAlive reports whether a process with the given PID exists. EPERM
+// means it exists but belongs to another user.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
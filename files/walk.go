@@ -0,0 +1,223 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry describes a file found while walking a directory tree.
+type Entry struct {
+	// Path is the file path joined with the walked root.
+	Path string
+
+	// Size is the file size in bytes.
+	Size int64
+
+	// ModTime is the last modification time.
+	ModTime time.Time
+
+	// Mode holds the permission and type bits. Symbolic links are not
+	// followed, so they have the fs.ModeSymlink bit set.
+	Mode fs.FileMode
+}
+
+// IsDir reports whether the entry is a directory.
+func (e Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// walkFunc is called for every entry accepted by the filters.
+// It may be called concurrently.
+type walkFunc func(entry Entry)
+
+// walkFiles visits the regular files of the tree under root that match the
+// filters of o. Directories are read by up to o.concurrency goroutines.
+// Files and directories vanishing during the walk are ignored.
+func walkFiles(ctx context.Context, root string, o *walkOptions, visit walkFunc) error {
+	return walkTree(ctx, root, o, false, visit)
+}
+
+// walkTree is walkFiles visiting directories, symbolic links and other
+// special files as well when all is set.
+func walkTree(ctx context.Context, root string, o *walkOptions, all bool, visit walkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return &fs.PathError{Op: "walk", Path: root, Err: ErrNotDir}
+	}
+
+	walker := &walker{
+		ctx:   ctx,
+		root:  root,
+		opts:  o,
+		all:   all,
+		visit: visit,
+		now:   time.Now(),
+		slots: make(chan struct{}, o.concurrency-1),
+	}
+
+	walker.wg.Add(1)
+	walker.walkDir(root, 1)
+	walker.wg.Wait()
+
+	if walker.err != nil {
+		return walker.err
+	}
+
+	return ctx.Err()
+}
+
+// walker holds the state of a concurrent walk.
+type walker struct {
+	ctx   context.Context //nolint:containedctx // scoped to a single walk
+	root  string
+	opts  *walkOptions
+	all   bool
+	visit walkFunc
+	now   time.Time
+
+	// slots limits the number of extra goroutines. The walking goroutine
+	// itself does not take a slot.
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// walkDir reads dir whose items are depth levels below the root.
+func (w *walker) walkDir(dir string, depth int) {
+	defer w.wg.Done()
+
+	if w.ctx.Err() != nil || w.failed() {
+		return
+	}
+
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) || dir == w.root {
+			w.fail(err)
+		}
+
+		return
+	}
+
+	for _, item := range items {
+		full := filepath.Join(dir, item.Name())
+		rel := w.rel(full)
+
+		if w.opts.excluded(rel) || (w.opts.skipHidden && strings.HasPrefix(item.Name(), ".")) {
+			continue
+		}
+
+		if item.Type().IsRegular() || w.all {
+			w.visitItem(full, rel, item)
+		}
+
+		if item.IsDir() && (w.opts.maxDepth <= 0 || depth < w.opts.maxDepth) {
+			w.wg.Add(1)
+
+			select {
+			case w.slots <- struct{}{}:
+				go func() {
+					defer func() { <-w.slots }()
+					w.walkDir(full, depth+1)
+				}()
+			default:
+				w.walkDir(full, depth+1)
+			}
+		}
+	}
+}
+
+// visitItem passes the item to the visit function if it matches the filters.
+func (w *walker) visitItem(full, rel string, item fs.DirEntry) {
+	info, err := item.Info()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			w.fail(err)
+		}
+
+		return
+	}
+
+	if w.opts.match(rel, info, w.now) {
+		w.visit(Entry{Path: full, Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode()})
+	}
+}
+
+// rel returns the slash separated path of full relative to the root.
+func (w *walker) rel(full string) string {
+	rel, err := filepath.Rel(w.root, full)
+	if err != nil {
+		return filepath.ToSlash(full)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func (w *walker) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *walker) failed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err != nil
+}
+
+// match reports whether a file passes all filters.
+func (o *walkOptions) match(rel string, info fs.FileInfo, now time.Time) bool {
+	if !o.selected(rel) {
+		return false
+	}
+
+	if info.Size() < o.minSize {
+		return false
+	}
+
+	age := now.Sub(info.ModTime())
+
+	if o.minAge > 0 && age < o.minAge {
+		return false
+	}
+
+	if o.maxAge > 0 && age > o.maxAge {
+		return false
+	}
+
+	return true
+}
+
+// matchAny reports whether the slash separated relative path matches any of
+// the glob patterns. Patterns without a "/" are matched against the base name.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
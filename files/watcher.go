@@ -0,0 +1,534 @@
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often the polling watcher scans the tree when
+// kernel notifications are not available.
+const DefaultPollInterval = time.Second
+
+// watchQueueSize is the capacity of the internal event and error queues.
+const watchQueueSize = 256
+
+// ErrEventOverflow indicates that the kernel dropped events because they
+// were not read fast enough. The tree should be rescanned.
+var ErrEventOverflow = errors.New("watch event queue overflow")
+
+// Op is a bit mask of the changes reported by an Event.
+type Op uint32
+
+const (
+	// Create means the file or directory was created or moved into the tree.
+	Create Op = 1 << iota
+
+	// Write means the file content was modified.
+	Write
+
+	// Remove means the file or directory was removed or moved out of the tree.
+	Remove
+
+	// Rename means the file or directory was moved inside the tree from
+	// Event.OldPath to Event.Path.
+	Rename
+)
+
+// String returns the names of the set bits like "CREATE|WRITE".
+func (op Op) String() string {
+	names := make([]string, 0)
+
+	for _, bit := range []struct {
+		op   Op
+		name string
+	}{{Create, "CREATE"}, {Write, "WRITE"}, {Remove, "REMOVE"}, {Rename, "RENAME"}} {
+		if op&bit.op != 0 {
+			names = append(names, bit.name)
+		}
+	}
+
+	return strings.Join(names, "|")
+}
+
+// Has reports whether op contains all bits of other.
+func (op Op) Has(other Op) bool {
+	return op&other == other
+}
+
+// Event describes a change of a file or directory in the watched tree.
+type Event struct {
+	// Path is the path joined with the watched root.
+	Path string
+
+	// OldPath is the previous path of a Rename.
+	OldPath string
+
+	// Op holds the changes. With debouncing several changes of the same
+	// path are merged, e.g. Create|Write for a new file being filled.
+	Op Op
+}
+
+// watchBackend produces the raw events of a tree.
+type watchBackend interface {
+	// run delivers events until close is called.
+	run(emit func(Event), fail func(error))
+	close() error
+}
+
+// Watcher reports changes in a directory tree. It uses inotify on Linux and
+// polls the tree on other platforms or when WithPollInterval is set.
+//
+// Example usage:
+//
+//	w, err := NewWatcher("/srv/uploads", WithWatchInclude("*.csv"), WithDebounce(2*time.Second))
+//	if err != nil {
+//	    return err
+//	}
+//	defer w.Close()
+//
+//	for {
+//	    select {
+//	    case event, ok := <-w.Events():
+//	        if !ok {
+//	            return nil
+//	        }
+//	        if event.Op.Has(Create) {
+//	            process(event.Path)
+//	        }
+//	    case err := <-w.Errors():
+//	        log.Println(err)
+//	    }
+//	}
+type Watcher struct {
+	root    string
+	opts    *watchOptions
+	backend watchBackend
+
+	raw    chan Event
+	events chan Event
+	errors chan error
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewWatcher starts watching the tree under root. Changes made after it
+// returns are reported.
+//
+// Supported options: WithWatchInclude and WithWatchExclude filtering the
+// events by path, WithDebounce and WithPollInterval. Excluded directories
+// are not watched at all.
+func NewWatcher(root string, opts ...WatchOption) (*Watcher, error) {
+	o := newWatchOptions(opts...)
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "watch", Path: root, Err: ErrNotDir}
+	}
+
+	var backend watchBackend
+
+	if o.pollInterval > 0 {
+		backend, err = newPollBackend(root, o, o.pollInterval)
+	} else {
+		backend, err = newNativeBackend(root, o)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		root:    root,
+		opts:    o,
+		backend: backend,
+		raw:     make(chan Event, watchQueueSize),
+		events:  make(chan Event),
+		errors:  make(chan error, watchQueueSize),
+		done:    make(chan struct{}),
+	}
+
+	w.wg.Add(2)
+
+	go func() {
+		defer w.wg.Done()
+		defer close(w.raw)
+
+		backend.run(w.emit, w.fail)
+	}()
+
+	go w.dispatch()
+
+	return w, nil
+}
+
+// Events returns the channel of changes. It is closed by Close.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Errors returns the channel of errors occurring while watching. Errors are
+// dropped when the channel is not drained.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close stops watching and closes the Events channel. Pending debounced
+// events are dropped.
+func (w *Watcher) Close() error {
+	err := os.ErrClosed
+
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.backend.close()
+		w.wg.Wait()
+	})
+
+	return err
+}
+
+// emit passes a raw event of the backend through the filters.
+func (w *Watcher) emit(event Event) {
+	rel, err := filepath.Rel(w.root, event.Path)
+	if err != nil {
+		return
+	}
+
+	rel = filepath.ToSlash(rel)
+
+	if w.opts.excluded(rel) || !w.opts.selected(rel) {
+		return
+	}
+
+	select {
+	case w.raw <- event:
+	case <-w.done:
+	}
+}
+
+func (w *Watcher) fail(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// dispatch debounces the raw events and delivers them to the consumer.
+func (w *Watcher) dispatch() {
+	defer w.wg.Done()
+	defer close(w.events)
+
+	if w.opts.debounce <= 0 {
+		for event := range w.raw {
+			if !w.deliver(event) {
+				return
+			}
+		}
+
+		return
+	}
+
+	var (
+		pending   = make(map[string]Event)
+		deadlines = make(map[string]time.Time)
+		timer     = time.NewTimer(time.Hour)
+	)
+
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.raw:
+			if !ok {
+				return
+			}
+
+			if previous, ok := pending[event.Path]; ok {
+				event.Op |= previous.Op
+				if event.OldPath == "" {
+					event.OldPath = previous.OldPath
+				}
+			}
+
+			pending[event.Path] = event
+			deadlines[event.Path] = time.Now().Add(w.opts.debounce)
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		due := make([]string, 0)
+		next := time.Duration(-1)
+
+		for path, deadline := range deadlines {
+			if wait := deadline.Sub(now); wait > 0 {
+				if next < 0 || wait < next {
+					next = wait
+				}
+
+				continue
+			}
+
+			due = append(due, path)
+		}
+
+		sort.Slice(due, func(i, j int) bool {
+			return deadlines[due[i]].Before(deadlines[due[j]])
+		})
+
+		for _, path := range due {
+			event := pending[path]
+
+			delete(pending, path)
+			delete(deadlines, path)
+
+			if !w.deliver(event) {
+				return
+			}
+		}
+
+		timer.Stop()
+
+		if next >= 0 {
+			timer.Reset(next)
+		}
+	}
+}
+
+// deliver sends the event to the consumer. It returns false once the
+// watcher is closed.
+func (w *Watcher) deliver(event Event) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// fileState is the snapshot of a path used by the polling backend.
+type fileState struct {
+	id      fileID
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// pollBackend detects changes by comparing snapshots of the tree.
+type pollBackend struct {
+	root     string
+	opts     *watchOptions
+	interval time.Duration
+	snapshot map[string]fileState
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPollBackend(root string, o *watchOptions, interval time.Duration) (*pollBackend, error) {
+	b := &pollBackend{
+		root:     root,
+		opts:     o,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+
+	snapshot, err := b.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	b.snapshot = snapshot
+
+	return b, nil
+}
+
+func (b *pollBackend) run(emit func(Event), fail func(error)) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+
+		snapshot, err := b.scan()
+		if err != nil {
+			fail(err)
+
+			continue
+		}
+
+		for _, event := range diffSnapshots(b.snapshot, snapshot) {
+			emit(event)
+		}
+
+		b.snapshot = snapshot
+	}
+}
+
+func (b *pollBackend) close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+
+	return nil
+}
+
+// scan takes a snapshot of the tree skipping excluded directories.
+func (b *pollBackend) scan() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+
+	err := filepath.WalkDir(b.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != b.root {
+				return nil
+			}
+
+			return err
+		}
+
+		if path == b.root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(b.root, path)
+		if err != nil {
+			return err
+		}
+
+		if b.opts.excluded(filepath.ToSlash(rel)) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		state := fileState{size: info.Size(), modTime: info.ModTime(), isDir: info.IsDir()}
+
+		if id, ok := fileIdentity(info); ok {
+			state.id = id
+		}
+
+		snapshot[path] = state
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// diffSnapshots returns the events turning old into current. A path
+// disappearing while another one with the same inode appears is a rename;
+// the content of a renamed directory is not reported separately.
+func diffSnapshots(old, current map[string]fileState) []Event {
+	removed := make(map[fileID]string)
+
+	for path, state := range old {
+		if _, ok := current[path]; !ok && state.id != (fileID{}) {
+			removed[state.id] = path
+		}
+	}
+
+	created := make([]string, 0)
+
+	for path := range current {
+		if _, ok := old[path]; !ok {
+			created = append(created, path)
+		}
+	}
+
+	// Parents sort before their children.
+	sort.Strings(created)
+
+	events := make([]Event, 0)
+	renamed := make(map[string]string)
+	moved := make(map[string]bool)
+
+	for _, path := range created {
+		oldPath, ok := removed[current[path].id]
+		if !ok {
+			events = append(events, Event{Path: path, Op: Create})
+
+			continue
+		}
+
+		moved[oldPath] = true
+
+		if !insideRenamedDir(renamed, oldPath, path) {
+			events = append(events, Event{Path: path, OldPath: oldPath, Op: Rename})
+		}
+
+		if current[path].isDir {
+			renamed[path] = oldPath
+		}
+	}
+
+	gone := make([]string, 0)
+
+	for path := range old {
+		if _, ok := current[path]; !ok && !moved[path] {
+			gone = append(gone, path)
+		}
+	}
+
+	sort.Strings(gone)
+
+	for _, path := range gone {
+		events = append(events, Event{Path: path, Op: Remove})
+	}
+
+	changed := make([]string, 0)
+
+	for path, state := range current {
+		previous, ok := old[path]
+		if ok && !state.isDir && (state.size != previous.size || !state.modTime.Equal(previous.modTime)) {
+			changed = append(changed, path)
+		}
+	}
+
+	sort.Strings(changed)
+
+	for _, path := range changed {
+		events = append(events, Event{Path: path, Op: Write})
+	}
+
+	return events
+}
+
+// insideRenamedDir reports whether moving oldPath to path is explained by the
+// rename of one of its parent directories.
+func insideRenamedDir(renamed map[string]string, oldPath, path string) bool {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if oldDir, ok := renamed[dir]; ok {
+			return oldPath == oldDir+path[len(dir):]
+		}
+	}
+
+	return false
+}
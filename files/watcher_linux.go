@@ -0,0 +1,251 @@
+//go:build linux
+
+package files
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// inotifyMask is the set of events watched on every directory.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF |
+	syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW
+
+// inotifyBuffer fits a batch of events with names of maximum length.
+const inotifyBuffer = 64 * (syscall.SizeofInotifyEvent + syscall.NAME_MAX + 1)
+
+// movedFrom is the first half of a rename waiting for its IN_MOVED_TO.
+type movedFrom struct {
+	path  string
+	isDir bool
+}
+
+// inotifyBackend watches every directory of the tree with inotify(7).
+// Its maps are only used by the run goroutine after construction.
+type inotifyBackend struct {
+	root string
+	opts *watchOptions
+	file *os.File
+	fd   int
+
+	paths map[int]string
+	moves map[uint32]movedFrom
+}
+
+func newNativeBackend(root string, o *watchOptions) (watchBackend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	// A non-blocking descriptor lets the runtime poller interrupt Read on Close.
+	b := &inotifyBackend{
+		root:  root,
+		opts:  o,
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		fd:    fd,
+		paths: make(map[int]string),
+		moves: make(map[uint32]movedFrom),
+	}
+
+	if err := b.addTree(root, nil); err != nil {
+		b.file.Close()
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *inotifyBackend) run(emit func(Event), fail func(error)) {
+	buf := make([]byte, inotifyBuffer)
+
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				fail(err)
+			}
+
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int(int32(binary.NativeEndian.Uint32(buf[offset:]))) //nolint:gosec // wd is a signed int32
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			cookie := binary.NativeEndian.Uint32(buf[offset+8:])
+			size := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+
+			offset += syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[offset:min(offset+size, n)]), "\x00")
+			offset += size
+
+			b.handle(wd, mask, cookie, name, emit, fail)
+		}
+
+		// Renames are reported as adjacent events, an IN_MOVED_FROM left
+		// alone means the file left the tree.
+		b.flushMoves(emit)
+	}
+}
+
+func (b *inotifyBackend) close() error {
+	return b.file.Close()
+}
+
+func (b *inotifyBackend) handle(wd int, mask, cookie uint32, name string, emit func(Event), fail func(error)) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		fail(ErrEventOverflow)
+
+		return
+	}
+
+	dir, ok := b.paths[wd]
+	if !ok {
+		return
+	}
+
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(b.paths, wd)
+
+		return
+	}
+
+	if mask&syscall.IN_DELETE_SELF != 0 {
+		// Other directories are reported by their parent.
+		if dir == b.root {
+			emit(Event{Path: dir, Op: Remove})
+		}
+
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+
+	switch {
+	case mask&syscall.IN_CREATE != 0:
+		emit(Event{Path: path, Op: Create})
+
+		if isDir {
+			// Files created before the watch was added are reported here.
+			if err := b.addTree(path, emit); err != nil {
+				fail(err)
+			}
+		}
+	case mask&syscall.IN_MODIFY != 0:
+		emit(Event{Path: path, Op: Write})
+	case mask&syscall.IN_DELETE != 0:
+		emit(Event{Path: path, Op: Remove})
+	case mask&syscall.IN_MOVED_FROM != 0:
+		b.moves[cookie] = movedFrom{path: path, isDir: isDir}
+	case mask&syscall.IN_MOVED_TO != 0:
+		from, ok := b.moves[cookie]
+		if !ok {
+			emit(Event{Path: path, Op: Create})
+
+			if isDir {
+				if err := b.addTree(path, emit); err != nil {
+					fail(err)
+				}
+			}
+
+			return
+		}
+
+		delete(b.moves, cookie)
+
+		if isDir {
+			b.renameWatches(from.path, path)
+		}
+
+		emit(Event{Path: path, OldPath: from.path, Op: Rename})
+	}
+}
+
+// addTree watches dir and its subdirectories. With emit set every entry
+// found below dir is reported as created.
+func (b *inotifyBackend) addTree(dir string, emit func(Event)) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != b.root {
+				return nil
+			}
+
+			return err
+		}
+
+		if path != b.root {
+			rel, err := filepath.Rel(b.root, path)
+			if err != nil {
+				return err
+			}
+
+			if b.opts.excluded(filepath.ToSlash(rel)) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+		}
+
+		if emit != nil && path != dir {
+			emit(Event{Path: path, Op: Create})
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(b.fd, path, inotifyMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOENT) && path != b.root {
+				return filepath.SkipDir
+			}
+
+			return &fs.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+		}
+
+		b.paths[wd] = path
+
+		return nil
+	})
+}
+
+// renameWatches updates the paths of the watches inside a renamed directory.
+func (b *inotifyBackend) renameWatches(oldPath, newPath string) {
+	for wd, path := range b.paths {
+		if path != oldPath && !strings.HasPrefix(path, oldPath+string(filepath.Separator)) {
+			continue
+		}
+
+		b.paths[wd] = newPath + path[len(oldPath):]
+	}
+}
+
+// flushMoves reports the files moved out of the tree as removed and drops
+// the watches of directories among them.
+func (b *inotifyBackend) flushMoves(emit func(Event)) {
+	for cookie, from := range b.moves {
+		delete(b.moves, cookie)
+
+		if from.isDir {
+			for wd, path := range b.paths {
+				if path == from.path || strings.HasPrefix(path, from.path+string(filepath.Separator)) {
+					_, _ = syscall.InotifyRmWatch(b.fd, uint32(wd)) //nolint:gosec // wd is never negative
+
+					delete(b.paths, wd)
+				}
+			}
+		}
+
+		emit(Event{Path: from.path, Op: Remove})
+	}
+}
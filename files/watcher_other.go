@@ -0,0 +1,8 @@
+//go:build !linux
+
+package files
+
+// newNativeBackend falls back to polling where inotify is not available.
+func newNativeBackend(root string, o *watchOptions) (watchBackend, error) {
+	return newPollBackend(root, o, DefaultPollInterval)
+}
@@ -0,0 +1,210 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watcherBackends runs a test with the native and the polling backend.
+var watcherBackends = map[string][]WatchOption{
+	"native":  nil,
+	"polling": {WithPollInterval(20 * time.Millisecond)},
+}
+
+// waitEvent returns the first event matching path and op or fails the test.
+func waitEvent(t *testing.T, w *Watcher, path string, op Op) Event {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case event, ok := <-w.Events():
+			require.True(t, ok, "events channel closed")
+
+			if event.Path == path && event.Op.Has(op) {
+				return event
+			}
+		case err := <-w.Errors():
+			require.NoError(t, err)
+		case <-timeout:
+			require.Failf(t, "timeout", "no %s event for %s", op, path)
+		}
+	}
+}
+
+// collectEvents gathers the events arriving within d.
+func collectEvents(w *Watcher, d time.Duration) []Event {
+	events := make([]Event, 0)
+	timeout := time.After(d)
+
+	for {
+		select {
+		case event := <-w.Events():
+			events = append(events, event)
+		case <-timeout:
+			return events
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	for name, backend := range watcherBackends {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(root, "sub"), 0o755))
+
+			w, err := NewWatcher(root, backend...)
+			require.NoError(t, err)
+			defer w.Close()
+
+			file := filepath.Join(root, "sub", "a.txt")
+			require.NoError(t, os.WriteFile(file, []byte("a"), 0o644))
+			waitEvent(t, w, file, Create)
+
+			time.Sleep(50 * time.Millisecond)
+			require.NoError(t, os.WriteFile(file, []byte("changed"), 0o644))
+			waitEvent(t, w, file, Write)
+
+			renamed := filepath.Join(root, "b.txt")
+			require.NoError(t, os.Rename(file, renamed))
+			event := waitEvent(t, w, renamed, Rename)
+			assert.Equal(t, file, event.OldPath)
+
+			require.NoError(t, os.Remove(renamed))
+			waitEvent(t, w, renamed, Remove)
+
+			nested := filepath.Join(root, "new", "deep")
+			require.NoError(t, os.MkdirAll(nested, 0o755))
+			waitEvent(t, w, nested, Create)
+
+			deep := filepath.Join(nested, "c.txt")
+			require.NoError(t, os.WriteFile(deep, []byte("c"), 0o644))
+			waitEvent(t, w, deep, Create)
+
+			moved := filepath.Join(root, "moved")
+			require.NoError(t, os.Rename(filepath.Join(root, "new"), moved))
+			waitEvent(t, w, moved, Rename)
+
+			// Watches follow the renamed directory.
+			late := filepath.Join(moved, "deep", "d.txt")
+			require.NoError(t, os.WriteFile(late, []byte("d"), 0o644))
+			waitEvent(t, w, late, Create)
+
+			require.NoError(t, w.Close())
+
+			_, ok := <-w.Events()
+			assert.False(t, ok)
+			assert.ErrorIs(t, w.Close(), os.ErrClosed)
+		})
+	}
+}
+
+func TestWatcherFilters(t *testing.T) {
+	for name, backend := range watcherBackends {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(root, "tmp"), 0o755))
+
+			opts := append([]WatchOption{WithWatchInclude("*.csv"), WithWatchExclude("tmp")}, backend...)
+
+			w, err := NewWatcher(root, opts...)
+			require.NoError(t, err)
+			defer w.Close()
+
+			require.NoError(t, os.WriteFile(filepath.Join(root, "tmp", "x.csv"), []byte("x"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "skip.txt"), []byte("x"), 0o644))
+
+			csv := filepath.Join(root, "data.csv")
+			require.NoError(t, os.WriteFile(csv, []byte("x"), 0o644))
+
+			event := waitEvent(t, w, csv, Create)
+			assert.Equal(t, csv, event.Path)
+
+			for _, event := range collectEvents(w, 100*time.Millisecond) {
+				assert.Equal(t, csv, event.Path)
+			}
+		})
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	root := t.TempDir()
+
+	w, err := NewWatcher(root, WithDebounce(200*time.Millisecond))
+	require.NoError(t, err)
+	defer w.Close()
+
+	name := filepath.Join(root, "upload.bin")
+
+	f, err := os.Create(name)
+	require.NoError(t, err)
+
+	for range 10 {
+		_, err := f.Write([]byte("chunk"))
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.NoError(t, f.Close())
+
+	events := collectEvents(w, time.Second)
+	require.Len(t, events, 1)
+	assert.Equal(t, Event{Path: name, Op: Create | Write}, events[0])
+}
+
+func TestWatcherErrors(t *testing.T) {
+	root := t.TempDir()
+
+	_, err := NewWatcher(filepath.Join(root, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	file := filepath.Join(root, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	_, err = NewWatcher(file)
+	assert.ErrorIs(t, err, ErrNotDir)
+}
+
+func TestOpString(t *testing.T) {
+	assert.Equal(t, "CREATE|WRITE", (Create | Write).String())
+	assert.Equal(t, "RENAME", Rename.String())
+	assert.Equal(t, "", Op(0).String())
+	assert.True(t, (Create | Remove).Has(Remove))
+	assert.False(t, Write.Has(Create|Write))
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+
+	old := map[string]fileState{
+		"/r/a":     {id: fileID{1, 1}, size: 1, modTime: now},
+		"/r/b":     {id: fileID{1, 2}, size: 1, modTime: now},
+		"/r/d":     {id: fileID{1, 3}, isDir: true, modTime: now},
+		"/r/d/f":   {id: fileID{1, 4}, size: 1, modTime: now},
+		"/r/gone":  {id: fileID{1, 5}, size: 1, modTime: now},
+		"/r/write": {id: fileID{1, 6}, size: 1, modTime: now},
+	}
+
+	current := map[string]fileState{
+		"/r/a":     {id: fileID{1, 1}, size: 1, modTime: now},
+		"/r/c":     {id: fileID{1, 2}, size: 1, modTime: now},
+		"/r/e":     {id: fileID{1, 3}, isDir: true, modTime: now.Add(time.Second)},
+		"/r/e/f":   {id: fileID{1, 4}, size: 1, modTime: now},
+		"/r/new":   {id: fileID{1, 7}, size: 1, modTime: now},
+		"/r/write": {id: fileID{1, 6}, size: 2, modTime: now},
+	}
+
+	assert.Equal(t, []Event{
+		{Path: "/r/c", OldPath: "/r/b", Op: Rename},
+		{Path: "/r/e", OldPath: "/r/d", Op: Rename},
+		{Path: "/r/new", Op: Create},
+		{Path: "/r/gone", Op: Remove},
+		{Path: "/r/write", Op: Write},
+	}, diffSnapshots(old, current))
+}